@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+var (
+	dailyPickCmdDate  = ""
+	dailyPickCmdInfos = false
+	dailyPickCmdNum   = false
+	dailyPickCmdQuery = ""
+	dailyPickCmdSeed  = ""
+	dailyPickCmdShow  = false
+)
+
+var dailyPickCmd = &cobra.Command{
+	Use:   `daily-pick`,
+	Short: "Get the post of the day picked for a seed",
+	Long: `Get the informations of the post of the day picked for a seed, or show it with --show.
+
+The post is picked deterministically from the seed and the date, among posts published on
+or before the date, so everyone using the same seed gets the same post on a given day,
+without any shared server. Posts are picked among all published posts, or among indexed
+posts matching a search query with --query (see 'xkcd search --help' for its syntax), in
+which case everyone should use an up to date index to get the same post.
+
+Example: xkcd daily-pick --seed my-team --show`,
+	Aliases: []string{"daily"},
+	Args:    cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, _ []string) {
+		checkPickedPostFlags(cmd, dailyPickCmdShow, dailyPickCmdNum, dailyPickCmdInfos)
+	},
+	Run: func(cmd *cobra.Command, _ []string) {
+		date := time.Now()
+		if dailyPickCmdDate != "" {
+			var err error
+			date, err = time.ParseInLocation(time.DateOnly, dailyPickCmdDate, time.Local)
+			if err != nil {
+				fatal(cmd, "invalid date, expected YYYY-MM-DD")
+			}
+		}
+		var post *xkcd.Post
+		var err error
+		if dailyPickCmdQuery != "" {
+			checkIndexInitialized(cmd)
+			post, err = index.DailyPick(cmd.Context(), dailyPickCmdSeed, date, xkcdindex.Filter{Text: dailyPickCmdQuery})
+			checkQueryErr(cmd, err)
+		} else {
+			post, err = apiClient.GetDailyPick(cmd.Context(), dailyPickCmdSeed, date)
+		}
+		checkErr(err, cmd, "failed to get post of the day")
+		displayPickedPost(cmd, post, dailyPickCmdShow, dailyPickCmdNum, dailyPickCmdInfos)
+	},
+}
+
+func init() {
+	dailyPickCmd.Flags().StringVarP(&dailyPickCmdDate, "date", "d", "", "date of the pick, as YYYY-MM-DD, defaults to today")
+	dailyPickCmd.Flags().BoolVarP(&dailyPickCmdInfos, "infos", "i", false, "show post informations, with --show")
+	dailyPickCmd.Flags().BoolVarP(&dailyPickCmdNum, "num", "n", false, "only print the post number")
+	dailyPickCmd.Flags().StringVarP(&dailyPickCmdQuery, "query", "q", "", "only pick indexed posts matching this search query")
+	dailyPickCmd.Flags().StringVar(&dailyPickCmdSeed, "seed", "", "seed of the pick, shared by everyone who should get the same post")
+	dailyPickCmd.Flags().BoolVarP(&dailyPickCmdShow, "show", "s", false, "show the post image")
+	rootCmd.AddCommand(dailyPickCmd)
+}
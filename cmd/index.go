@@ -16,7 +16,7 @@ Index commands allow you to manipulate this index.`,
 }
 
 func checkIndexInitialized(cmd *cobra.Command) {
-	if index.Initized() {
+	if index.Initialized() {
 		return
 	}
 	fatal(
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jucrouzet/xkcd/internal/pkg/cli"
+)
+
+var indexBackfillMetadataCmd = &cobra.Command{
+	Use:   "backfill-metadata",
+	Short: "Compute metadata of offline images stored without it",
+	Long: `Compute and store the content type, size, dimensions and SHA-256 sum of offline images
+that were stored in the index before this metadata was recorded.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		checkIndexInitialized(cmd)
+		updated, err := index.BackfillImageMetadata(cmd.Context())
+		checkErr(err, cmd, "failed to backfill image metadata")
+		checkErr(cli.DisplaySummary(
+			cmd.OutOrStdout(),
+			indexBackfillMetadataSummary{Updated: updated},
+			fmt.Sprintf("Updated metadata of %d images.", updated),
+			json,
+		), cmd, "failed to display backfill summary")
+	},
+}
+
+// indexBackfillMetadataSummary is the JSON output of the backfill-metadata command.
+type indexBackfillMetadataSummary struct {
+	Updated uint `json:"updated"`
+}
+
+func init() {
+	indexCmd.AddCommand(indexBackfillMetadataCmd)
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jucrouzet/xkcd/internal/pkg/cli"
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+var (
+	indexExportCmdFormat = ""
+)
+
+var indexExportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Export indexed posts to a file",
+	Long: `Export indexed posts to a file, or to the standard output if no file is given.
+
+Supported formats are:
+  ndjson  one JSON post record per line
+  csv     posts metadata, with a header line
+  tar     an archive holding posts in posts.ndjson, and stored images in images/<num>.<ext>
+  zip     same as tar, in a zip archive
+
+The format is inferred from the file extension if not set with --format, and defaults to ndjson.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkIndexInitialized(cmd)
+		format := getIndexFileFormat(cmd, indexExportCmdFormat, args)
+		var out io.Writer = cmd.OutOrStdout()
+		var f *os.File
+		if len(args) > 0 && args[0] != "-" {
+			var err error
+			f, err = os.Create(args[0])
+			checkErr(err, cmd, fmt.Sprintf("failed to create %s", args[0]))
+			out = f
+		}
+		count, err := index.Export(cmd.Context(), out, format)
+		if f != nil {
+			// Close may report a failed write, which must fail the export.
+			errClose := f.Close()
+			if err == nil {
+				err = errClose
+			}
+		}
+		checkErr(err, cmd, "failed to export index")
+		if out != cmd.OutOrStdout() {
+			checkErr(cli.DisplaySummary(
+				cmd.OutOrStdout(),
+				indexExportSummary{Exported: count},
+				fmt.Sprintf("Exported %d posts to %s.", count, args[0]),
+				json,
+			), cmd, "failed to display export summary")
+		}
+	},
+}
+
+// indexExportSummary is the JSON output of the export command, when posts are exported to a file.
+type indexExportSummary struct {
+	Exported uint `json:"exported"`
+}
+
+// getIndexFileFormat returns the export or import format set by flag, or inferred from the file argument.
+func getIndexFileFormat(cmd *cobra.Command, flag string, args []string) xkcdindex.Format {
+	if flag != "" {
+		format, err := xkcdindex.ParseFormat(flag)
+		if err != nil {
+			fatal(cmd, err.Error())
+		}
+		return format
+	}
+	if len(args) == 0 || args[0] == "-" {
+		return xkcdindex.FormatNDJSON
+	}
+	format, err := xkcdindex.FormatFromPath(args[0])
+	if err != nil {
+		return xkcdindex.FormatNDJSON
+	}
+	return format
+}
+
+func init() {
+	indexExportCmd.Flags().StringVarP(&indexExportCmdFormat, "format", "f", "", "export format: ndjson, csv, tar or zip")
+	indexCmd.AddCommand(indexExportCmd)
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/jucrouzet/xkcd/internal/pkg/cli"
+)
+
+var indexFailuresCmd = &cobra.Command{
+	Use:   "failures",
+	Short: "List posts which failed to be indexed",
+	Long: `List posts which failed to be indexed, with their last error.
+Failed posts are retried by each 'index update', with a delay doubling after each failed attempt.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		checkIndexInitialized(cmd)
+		failures, err := index.Failures(cmd.Context())
+		checkErr(err, cmd, "failed to list failed posts")
+		checkErr(cli.DisplayFailures(cmd.OutOrStdout(), failures, json), cmd, "failed to display failed posts")
+	},
+}
+
+func init() {
+	indexCmd.AddCommand(indexFailuresCmd)
+}
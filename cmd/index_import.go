@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jucrouzet/xkcd/internal/pkg/cli"
+)
+
+var (
+	indexImportCmdFormat = ""
+)
+
+var indexImportCmd = &cobra.Command{
+	Use:   "import file",
+	Short: "Import posts from an export file",
+	Long: `Import posts from a file created by the export command, or from the standard input if file is "-".
+Imported posts are validated, then added to the index or replace indexed posts with the same number.
+The import is aborted without changing the index if a post is invalid.
+
+Images in tar and zip archives are imported only if the index is in offline mode.
+The format is inferred from the file extension if not set with --format, and defaults to ndjson.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkIndexInitialized(cmd)
+		format := getIndexFileFormat(cmd, indexImportCmdFormat, args)
+		var in io.Reader = cmd.InOrStdin()
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			checkErr(err, cmd, fmt.Sprintf("failed to open %s", args[0]))
+			defer f.Close()
+			in = f
+		}
+		count, err := index.Import(cmd.Context(), in, format)
+		checkErr(err, cmd, "failed to import index")
+		checkErr(cli.DisplaySummary(
+			cmd.OutOrStdout(),
+			indexImportSummary{Imported: count},
+			fmt.Sprintf("Imported %d posts.", count),
+			json,
+		), cmd, "failed to display import summary")
+	},
+}
+
+// indexImportSummary is the JSON output of the import command.
+type indexImportSummary struct {
+	Imported uint `json:"imported"`
+}
+
+func init() {
+	indexImportCmd.Flags().StringVarP(&indexImportCmdFormat, "format", "f", "", "import format: ndjson, csv, tar or zip")
+	indexCmd.AddCommand(indexImportCmd)
+}
@@ -2,10 +2,13 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
 )
 
 var (
 	indexInitCmdForce   = false
+	indexInitCmdHires   = false
 	indexInitCmdOffline = false
 )
 
@@ -13,16 +16,20 @@ var indexInitCmd = &cobra.Command{
 	Use:   `init`,
 	Short: "Initialize the index",
 	Run: func(cmd *cobra.Command, _ []string) {
-		if index.Initized() && !indexInitCmdForce {
+		if index.Initialized() && !indexInitCmdForce {
 			fatal(cmd, "index is already initialized")
 			return
 		}
 		checkErr(index.Init(cmd.Context(), indexInitCmdForce, indexInitCmdOffline), cmd, "failed to initialize index")
+		if indexInitCmdHires {
+			checkErr(index.SetImageVariant(cmd.Context(), xkcd.Variant2x), cmd, "failed to set image variant")
+		}
 	},
 }
 
 func init() {
 	indexInitCmd.Flags().BoolVarP(&indexInitCmdForce, "force", "f", false, "force reinitialization of the index (all previous data is lost)")
+	indexInitCmd.Flags().BoolVar(&indexInitCmdHires, "hires", false, "store high resolution (2x) images in offline mode, when posts have one")
 	indexInitCmd.Flags().BoolVar(&indexInitCmdOffline, "offline", false, "initialize the index with offline mode, image content will be stored in index for offline")
 	indexCmd.AddCommand(indexInitCmd)
 }
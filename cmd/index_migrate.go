@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/jucrouzet/xkcd/internal/pkg/cli"
+)
+
+var (
+	indexMigrateCmdDryRun = false
+)
+
+// indexMigrateCmd opens the index without applying migrations, see the root command.
+var indexMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate the index to the current schema version",
+	Long: `Apply pending schema migrations to the index.
+Migrations are also applied automatically whenever the index is opened by another command.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		checkIndexInitialized(cmd)
+		if indexMigrateCmdDryRun {
+			pending, err := index.PendingMigrations(cmd.Context())
+			checkErr(err, cmd, "failed to get pending migrations")
+			checkErr(cli.DisplayMigrations(cmd.OutOrStdout(), pending, false, json), cmd, "failed to display migrations")
+			return
+		}
+		applied, err := index.Migrate(cmd.Context())
+		checkErr(cli.DisplayMigrations(cmd.OutOrStdout(), applied, true, json), cmd, "failed to display migrations")
+		checkErr(err, cmd, "failed to migrate index")
+	},
+}
+
+func init() {
+	indexMigrateCmd.Flags().BoolVar(&indexMigrateCmdDryRun, "dry-run", false, "only print pending migrations, do not apply them")
+	indexCmd.AddCommand(indexMigrateCmd)
+}
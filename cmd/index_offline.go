@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jucrouzet/xkcd/internal/pkg/cli"
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+var (
+	indexOfflineEnableCmdHires   = false
+	indexOfflineEnableCmdWorkers = uint(5)
+)
+
+var indexOfflineCmd = &cobra.Command{
+	Use:   "offline operation",
+	Short: "Switch the index between online and offline modes",
+	Long: `In offline mode, post images are stored in the index so they can be shown without network access.
+Offline commands allow you to switch an existing index between modes without reinitializing it.`,
+}
+
+var indexOfflineEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Switch the index to offline mode, storing images of indexed posts",
+	Long: `Switch the index to offline mode, and store the images of all indexed posts.
+Images are stored as soon as they are fetched: if the command fails or times out, run it again to resume.
+With --hires, high resolution (2x) images are stored, for posts having one. Images already stored are kept,
+disable offline mode first to store them again.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		checkIndexInitialized(cmd)
+		if cmd.Flags().Changed("hires") {
+			variant := xkcd.Variant1x
+			if indexOfflineEnableCmdHires {
+				variant = xkcd.Variant2x
+			}
+			checkErr(index.SetImageVariant(cmd.Context(), variant), cmd, "failed to set image variant")
+		}
+		stored, err := index.EnableOffline(cmd.Context(), apiClient, indexOfflineEnableCmdWorkers)
+		checkErr(err, cmd, fmt.Sprintf("failed to store images (%d stored), run the command again to resume", stored))
+		checkErr(cli.DisplaySummary(
+			cmd.OutOrStdout(),
+			indexOfflineSummary{Offline: true, Stored: &stored},
+			fmt.Sprintf("Index is offline, stored %d images.", stored),
+			json,
+		), cmd, "failed to display offline summary")
+	},
+}
+
+var indexOfflineDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Switch the index to online mode, removing stored images",
+	Run: func(cmd *cobra.Command, _ []string) {
+		checkIndexInitialized(cmd)
+		checkErr(index.DisableOffline(cmd.Context()), cmd, "failed to disable offline mode")
+		checkErr(cli.DisplaySummary(
+			cmd.OutOrStdout(),
+			indexOfflineSummary{Offline: false},
+			"Index is online, stored images were removed.",
+			json,
+		), cmd, "failed to display offline summary")
+	},
+}
+
+// indexOfflineSummary is the JSON output of the offline commands, with the number of stored images when enabling
+// offline mode.
+type indexOfflineSummary struct {
+	Offline bool  `json:"offline"`
+	Stored  *uint `json:"stored,omitempty"`
+}
+
+func init() {
+	indexOfflineEnableCmd.Flags().BoolVar(&indexOfflineEnableCmdHires, "hires", false, "store high resolution (2x) images, when posts have one")
+	indexOfflineEnableCmd.Flags().UintVarP(&indexOfflineEnableCmdWorkers, "workers", "w", 5, "how many images should we fetch concurrently")
+	indexOfflineCmd.AddCommand(indexOfflineEnableCmd)
+	indexOfflineCmd.AddCommand(indexOfflineDisableCmd)
+	indexCmd.AddCommand(indexOfflineCmd)
+}
@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/jucrouzet/xkcd/internal/pkg/cli"
+)
+
+var indexStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show statistics about the index content",
+	Run: func(cmd *cobra.Command, _ []string) {
+		checkIndexInitialized(cmd)
+		stats, err := index.Stats(cmd.Context())
+		checkErr(err, cmd, "failed to get index statistics")
+		checkErr(cli.DisplayStats(cmd.OutOrStdout(), stats, json), cmd, "failed to display index statistics")
+	},
+}
+
+func init() {
+	indexCmd.AddCommand(indexStatsCmd)
+}
@@ -26,7 +26,19 @@ var indexUpdateCmd = &cobra.Command{
 			logger = logger.With(slog.Time("last_update", lastDate))
 		}
 		if !indexUpdateCmdForce && time.Since(lastDate).Hours() < 24 {
-			logger.Debug("index is up to date")
+			// Failed posts are retried as soon as their retry delay is over.
+			due, err := index.HasDueFailures(cmd.Context())
+			checkErrIndex(err, cmd, "failed to get failed posts")
+			if !due {
+				logger.Debug("index is up to date")
+				return
+			}
+			if indexUpdateCmdCheck {
+				fatal(cmd, "index has failed posts to retry")
+				return
+			}
+			logger.Debug("retrying failed posts")
+			checkErr(index.RetryFailures(cmd.Context(), apiClient, indexUpdateCmdWorkers), cmd, "failed to retry failed posts")
 			return
 		}
 		if indexUpdateCmdCheck {
@@ -38,6 +50,7 @@ var indexUpdateCmd = &cobra.Command{
 		checkErrIndex(err, cmd, "failed to get latest post")
 		if latest.Num <= lastNum {
 			logger.Debug("index is up to date")
+			checkErr(index.RetryFailures(cmd.Context(), apiClient, indexUpdateCmdWorkers), cmd, "failed to retry failed posts")
 			return
 		}
 		checkErr(index.Update(cmd.Context(), apiClient, lastNum+1, latest.Num, indexUpdateCmdWorkers), cmd, "failed to update index")
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/jucrouzet/xkcd/internal/pkg/cli"
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+var (
+	indexVerifyCmdRepair = false
+)
+
+var indexVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the index for corruption, missing posts and invalid posts or images",
+	Long: `Check the index database integrity, and find posts which are missing, or have an invalid date or URL.
+In offline mode, also find posts with an image which is not stored or cannot be decoded.
+Posts which were never published, like comic #404, are not reported as missing.
+
+With --repair, posts with problems are fetched again from xkcd API. Database integrity problems cannot be repaired,
+reinitialize the index with 'index init -f' if some are found.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		checkIndexInitialized(cmd)
+		problems, err := index.Verify(cmd.Context())
+		checkErr(err, cmd, "failed to verify index")
+		var fixed []xkcdindex.Problem
+		var errRepair error
+		if indexVerifyCmdRepair {
+			fixed, errRepair = index.Repair(cmd.Context(), apiClient, problems)
+		}
+		checkErr(cli.DisplayVerifyReport(cmd.OutOrStdout(), problems, fixed, indexVerifyCmdRepair, json), cmd, "failed to display verify report")
+		checkErr(errRepair, cmd, "failed to repair index")
+		if len(problems) > len(fixed) {
+			fatal(cmd, "index has problems")
+		}
+	},
+}
+
+func init() {
+	indexVerifyCmd.Flags().BoolVar(&indexVerifyCmdRepair, "repair", false, "fetch again posts with problems")
+	indexCmd.AddCommand(indexVerifyCmd)
+}
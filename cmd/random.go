@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jucrouzet/xkcd/internal/pkg/cli"
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+var (
+	randomCmdInfos      = false
+	randomCmdNum        = false
+	randomCmdQuery      = ""
+	randomCmdShow       = false
+	randomCmdTranscript = false
+	randomCmdYear       = uint(0)
+)
+
+var randomCmd = &cobra.Command{
+	Use:   `random`,
+	Short: "Get a random xkcd post",
+	Long: `Get the informations of a random xkcd post, or show it with --show.
+
+If the index is initialized, the post is picked among indexed posts, which can be filtered
+by publication year, transcript presence, or a search query (see 'xkcd search --help' for its
+syntax). Else, it is picked among all posts published.
+
+With --num, only the post number is printed, to be used by other commands:
+  xkcd show $(xkcd random --num --year 2010)`,
+	Aliases: []string{"r", "rand"},
+	Args:    cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, _ []string) {
+		checkPickedPostFlags(cmd, randomCmdShow, randomCmdNum, randomCmdInfos)
+	},
+	Run: func(cmd *cobra.Command, _ []string) {
+		var post *xkcd.Post
+		var err error
+		filter, filtered := getRandomFilter(cmd)
+		switch {
+		case index.Initialized():
+			post, err = index.Random(cmd.Context(), filter)
+			checkQueryErr(cmd, err)
+			if errors.Is(err, xkcd.ErrNoSuchPost) && !filtered {
+				// Nothing is indexed yet.
+				post, err = apiClient.GetRandom(cmd.Context())
+			}
+		case filtered:
+			checkIndexInitialized(cmd)
+		default:
+			post, err = apiClient.GetRandom(cmd.Context())
+		}
+		checkErr(err, cmd, "failed to get random post")
+		displayPickedPost(cmd, post, randomCmdShow, randomCmdNum, randomCmdInfos)
+	},
+}
+
+// checkPickedPostFlags checks the flags of commands picking a post, and prepares showing it if show is true.
+func checkPickedPostFlags(cmd *cobra.Command, show, num, infos bool) {
+	if show && num {
+		fatal(cmd, "--show and --num cannot be used together")
+	}
+	if infos && !show {
+		fatal(cmd, "--infos can only be used with --show")
+	}
+	if show {
+		initDisplayer(cmd)
+	}
+}
+
+// displayPickedPost displays a picked post number if num is true, its image if show is true, else its informations.
+func displayPickedPost(cmd *cobra.Command, post *xkcd.Post, show, num, infos bool) {
+	switch {
+	case num:
+		fmt.Fprintln(cmd.OutOrStdout(), post.Num)
+	case show:
+		// Posts are fetched again through the index, to get their stored image in offline mode.
+		post, err := index.Get(cmd.Context(), apiClient, post.Num)
+		checkErr(err, cmd, "failed to get post")
+		showPost(cmd, post, infos, false)
+	default:
+		checkErr(cli.DisplayPostInfos(cmd.OutOrStdout(), post, json), cmd, "failed to display post informations")
+	}
+}
+
+// getRandomFilter returns the filter of indexed posts to pick from, and whether any filter flag is set.
+func getRandomFilter(cmd *cobra.Command) (xkcdindex.Filter, bool) {
+	var filter xkcdindex.Filter
+	filtered := false
+	if cmd.Flags().Changed("year") {
+		filter.From = time.Date(int(randomCmdYear), time.January, 1, 0, 0, 0, 0, time.Local)
+		filter.To = filter.From.AddDate(1, 0, 0)
+		filtered = true
+	}
+	if cmd.Flags().Changed("transcript") {
+		filter.HasTranscript = &randomCmdTranscript
+		filtered = true
+	}
+	if randomCmdQuery != "" {
+		filter.Text = randomCmdQuery
+		filtered = true
+	}
+	return filter, filtered
+}
+
+func init() {
+	randomCmd.Flags().BoolVarP(&randomCmdInfos, "infos", "i", false, "show post informations, with --show")
+	randomCmd.Flags().BoolVarP(&randomCmdNum, "num", "n", false, "only print the post number")
+	randomCmd.Flags().StringVarP(&randomCmdQuery, "query", "q", "", "only pick indexed posts matching this search query")
+	randomCmd.Flags().BoolVarP(&randomCmdShow, "show", "s", false, "show the post image")
+	randomCmd.Flags().BoolVar(&randomCmdTranscript, "transcript", false, "only pick indexed posts with (or without, with --transcript=false) a transcript")
+	randomCmd.Flags().UintVarP(&randomCmdYear, "year", "y", 0, "only pick indexed posts published this year")
+	rootCmd.AddCommand(randomCmd)
+}
@@ -4,6 +4,7 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -19,21 +20,25 @@ import (
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 
-	"github.com/jucrouzet/xkcd/internal/pkg/cli"
 	"github.com/jucrouzet/xkcd/pkg/xkcd"
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
 )
 
 var (
 	apiClient     *xkcd.Client
 	contextCancel context.CancelFunc
 	ctx           context.Context
-	index         *cli.Index
+	index         *xkcdindex.Index
 	logger        *slog.Logger
 	outToClose    io.Closer
 
 	build             = "development"
+	cache             = false
+	cacheDir          = ""
 	indexPath         = ""
 	json              = false
+	maxImageBytes     = xkcdindex.DefaultMaxImageBytes
+	maxImagePixels    = int64(50_000_000)
 	noColor           = false
 	outIsATTY         = false
 	outputContentType = "text/plain"
@@ -46,7 +51,14 @@ var (
 var rootCmd = &cobra.Command{
 	Use:   "xkcd",
 	Short: "xkcd in your terminal",
-	Long:  `Get your daily dose of xkcd comic, search for a post, or browse them, right from the terminal.`,
+	Long: `Get your daily dose of xkcd comic, search for a post, or browse them, right from the terminal.
+
+Exit codes:
+  0  success
+  1  failure
+  3  the requested post does not exist
+  4  xkcd servers returned an error
+  5  xkcd servers failed or timed out, trying again later may succeed`,
 	Run: func(cmd *cobra.Command, _ []string) {
 		checkErr(cmd.Help(), cmd)
 		os.Exit(0)
@@ -59,15 +71,29 @@ var rootCmd = &cobra.Command{
 			return
 		}
 		logger = getLogger(cmd)
-		apiClient = xkcd.New(
+		clientOpts := []xkcd.ClientOption{
 			xkcd.WithLogger(logger),
-		)
+			xkcd.WithRetryPolicy(xkcd.DefaultRetryPolicy()),
+			xkcd.WithMaxImageBytes(maxImageBytes),
+			xkcd.WithMaxImagePixels(maxImagePixels),
+		}
+		// Index commands fetch posts in bulk, caching their responses would duplicate the index.
+		if c := getCache(); c != nil && !isIndexCmd(cmd) {
+			clientOpts = append(clientOpts, xkcd.WithCache(c))
+		}
+		apiClient = xkcd.New(clientOpts...)
 		if noColor {
 			color.NoColor = true
 		}
 
 		var err error
-		index, err = cli.NewIndex(indexPath, logger)
+		index, err = xkcdindex.New(
+			indexPath,
+			logger,
+			xkcdindex.WithAutoMigrate(cmd != indexMigrateCmd),
+			xkcdindex.WithClient(apiClient),
+			xkcdindex.WithMaxImageBytes(maxImageBytes),
+		)
 		checkErr(err, cmd, "failed to open index")
 	},
 	PersistentPostRun: func(_ *cobra.Command, _ []string) {
@@ -94,6 +120,18 @@ func Execute() {
 	}
 }
 
+// Exit codes of the CLI.
+const (
+	// exitFailure is the exit code of errors without a more specific code.
+	exitFailure = 1
+	// exitNoSuchPost is the exit code when a requested post does not exist.
+	exitNoSuchPost = 3
+	// exitAPIError is the exit code when xkcd API or image servers failed.
+	exitAPIError = 4
+	// exitTemporary is the exit code when xkcd API or image servers failed, but trying again later may succeed.
+	exitTemporary = 5
+)
+
 func checkErr(err error, cmd *cobra.Command, message ...string) {
 	if err == nil {
 		return
@@ -102,17 +140,55 @@ func checkErr(err error, cmd *cobra.Command, message ...string) {
 	if len(message) > 0 {
 		logMsg = message[0]
 	}
-	logger.Warn("fatal error", slog.Any("error", err))
-	fatal(cmd, logMsg)
+	if !json {
+		logger.Warn("fatal error", slog.Any("error", err))
+	}
+	exit(cmd, logMsg, exitCode(err), errorAttrs(err)...)
 }
 
 func fatal(cmd *cobra.Command, message string) {
+	exit(cmd, message, exitFailure)
+}
+
+// exit prints message, as a log record with attrs in json mode, and exits with code.
+func exit(cmd *cobra.Command, message string, code int, attrs ...slog.Attr) {
 	if json {
-		logger.Error(message)
+		logger.LogAttrs(context.Background(), slog.LevelError, message, append(attrs, slog.Int("exit_code", code))...)
 	} else {
 		fmt.Fprintln(cmd.ErrOrStderr(), color.RedString("*** %s", message))
 	}
-	os.Exit(1)
+	os.Exit(code)
+}
+
+// exitCode returns the exit code for err.
+func exitCode(err error) int {
+	var apiErr *xkcd.APIError
+	switch {
+	case errors.Is(err, xkcd.ErrNoSuchPost):
+		return exitNoSuchPost
+	case errors.As(err, &apiErr) && apiErr.Retryable(), errors.Is(err, context.DeadlineExceeded):
+		return exitTemporary
+	case errors.Is(err, xkcd.ErrAPIError):
+		return exitAPIError
+	}
+	return exitFailure
+}
+
+// errorAttrs returns the log attributes describing err, with the details of API errors.
+func errorAttrs(err error) []slog.Attr {
+	attrs := []slog.Attr{slog.String("error", err.Error())}
+	var apiErr *xkcd.APIError
+	if errors.As(err, &apiErr) {
+		attrs = append(attrs, slog.Group(
+			"api_error",
+			slog.String("url", apiErr.URL),
+			slog.Int("status_code", apiErr.StatusCode),
+			slog.String("content_type", apiErr.ContentType),
+			slog.Bool("temporary", apiErr.Temporary()),
+			slog.Bool("retryable", apiErr.Retryable()),
+		))
+	}
+	return attrs
 }
 
 func getLogger(cmd *cobra.Command) *slog.Logger {
@@ -136,6 +212,29 @@ func getLogger(cmd *cobra.Command) *slog.Logger {
 	return slog.New(handler)
 }
 
+// getCache returns the cache of API responses, or nil if it is not enabled with --cache or cannot be used.
+// Cached responses, images included, are never evicted: the cache directory must be removed to clear them.
+func getCache() xkcd.Cache {
+	if !cache || cacheDir == "" {
+		return nil
+	}
+	cache, err := xkcd.NewDirCache(cacheDir)
+	if err != nil {
+		logger.Warn("failed to open cache, responses will not be cached", slog.String("error", err.Error()))
+		return nil
+	}
+	return cache
+}
+
+func isIndexCmd(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c == indexCmd {
+			return true
+		}
+	}
+	return false
+}
+
 type httpOut struct {
 	buffer  *bytes.Buffer
 	closed  *uint32
@@ -223,9 +322,16 @@ func init() {
 		panic(err)
 	}
 	indexPath = path.Join(home, ".xkcd.index")
+	if dir, err := os.UserCacheDir(); err == nil {
+		cacheDir = path.Join(dir, "xkcd")
+	}
 
+	rootCmd.PersistentFlags().BoolVar(&cache, "cache", false, "cache xkcd responses in --cache-dir, which is never pruned")
+	rootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", cacheDir, "Path to the directory caching xkcd responses with --cache")
 	rootCmd.PersistentFlags().StringVar(&indexPath, "index", indexPath, "Path to the index file")
 	rootCmd.PersistentFlags().BoolVarP(&json, "json", "j", false, "use the json format for logging and output")
+	rootCmd.PersistentFlags().Int64Var(&maxImageBytes, "max-image-bytes", maxImageBytes, "maximum size of fetched images in bytes, 0 for no limit")
+	rootCmd.PersistentFlags().Int64Var(&maxImagePixels, "max-image-pixels", maxImagePixels, "maximum number of pixels of decoded images, 0 for no limit")
 	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "do not use color in output even if terminal supports it")
 	rootCmd.PersistentFlags().StringVarP(&outputVal, "output", "o", "stdout", "output of the cli, can be 'stdout', 'stderr', a file path to be appended on or an url to POST on")
 	rootCmd.PersistentFlags().Uint32VarP(&timeout, "timeout", "t", 30000, "timeout in milliseconds")
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jucrouzet/xkcd/internal/pkg/cli"
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+var (
+	searchCmdLimit = uint(10)
+)
+
+var searchCmd = &cobra.Command{
+	Use:   `search query...`,
+	Short: "Search posts in the index",
+	Long: `Search indexed posts by their title, alt text, transcript and news.
+Posts matching the query are listed, the most relevant first.
+
+Terms are implicitly combined with AND, and can be combined with OR, negated with NOT
+or a leading -, and grouped with parentheses. A term is a word, a word prefix such as
+barr*, or a "quoted phrase", and can be restricted to a field:
+  title:, alt:, transcript:, news:
+
+Posts can also be filtered by number, year or date (YYYY, YYYY-MM or YYYY-MM-DD), with a
+single value, a range with optional bounds, or a comparison:
+  num:>1500  num:100..200  year:2010..2012  date:2010-05  before:2012  after:2010-06-15
+
+Example: xkcd search 'title:"little bobby" OR (alt:sql -year:<2008)'`,
+	Aliases: []string{"find"},
+	Args:    cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkIndexInitialized(cmd)
+		results, err := index.Search(cmd.Context(), strings.Join(args, " "), searchCmdLimit)
+		checkQueryErr(cmd, err)
+		checkErr(err, cmd, "failed to search index")
+		checkErr(cli.DisplaySearchResults(cmd.OutOrStdout(), results, json), cmd, "failed to display search results")
+	},
+}
+
+// checkQueryErr fails showing where the query is invalid if err is a *xkcdindex.QueryError.
+func checkQueryErr(cmd *cobra.Command, err error) {
+	var queryErr *xkcdindex.QueryError
+	if errors.As(err, &queryErr) {
+		fatal(cmd, fmt.Sprintf(
+			"%s\n    %s\n    %s^",
+			queryErr.Error(),
+			queryErr.Query,
+			strings.Repeat(" ", queryErr.Column()),
+		))
+	}
+}
+
+func init() {
+	searchCmd.Flags().UintVarP(&searchCmdLimit, "limit", "l", 10, "maximum number of posts to list")
+	rootCmd.AddCommand(searchCmd)
+}
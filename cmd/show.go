@@ -12,6 +12,7 @@ import (
 
 var (
 	displayer cli.Displayer
+	showHires = false
 	showInfos = false
 )
 
@@ -28,18 +29,7 @@ If no argument is provided, it defaults to the latest post.`,
 		return valids, cobra.ShellCompDirectiveNoFileComp
 	},
 	PreRun: func(cmd *cobra.Command, _ []string) {
-		if json {
-			fatal(cmd, "cannot show images in json mode")
-			return
-		}
-		if outIsATTY {
-			displayer = cli.GetDisplayer(logger)
-			if displayer == nil {
-				fatal(cmd, "terminal does not support image display")
-				return
-			}
-			return
-		}
+		initDisplayer(cmd)
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		var post *xkcd.Post
@@ -52,28 +42,53 @@ If no argument is provided, it defaults to the latest post.`,
 			post, err = index.Get(cmd.Context(), apiClient, uint(n))
 		}
 		checkErr(err, cmd, "failed to get latest post")
+		showPost(cmd, post, showInfos, showHires)
+	},
+}
 
-		if showInfos {
-			if !outIsATTY {
-				fatal(cmd, "showing informations in non-TTY mode is not supported")
-			}
-			checkErr(cli.DisplayPostInfos(cmd.OutOrStdout(), post, false), cmd, "failed to display post informations")
+// initDisplayer sets the displayer used to show post images if output is a terminal, and fails if it does not
+// support image display.
+func initDisplayer(cmd *cobra.Command) {
+	if json {
+		fatal(cmd, "cannot show images in json mode")
+		return
+	}
+	if outIsATTY {
+		displayer = cli.GetDisplayer(logger)
+		if displayer == nil {
+			fatal(cmd, "terminal does not support image display")
+			return
 		}
+	}
+}
 
-		if displayer != nil {
-			checkErr(cli.DisplayPostImage(cmd.Context(), cmd.OutOrStdout(), post, displayer), cmd, "failed to display post")
-			return
+// showPost shows the post image, preceded by its informations if infos is true, in high resolution if hires is true.
+func showPost(cmd *cobra.Command, post *xkcd.Post, infos, hires bool) {
+	variant := xkcd.Variant1x
+	if hires {
+		variant = xkcd.Variant2x
+	}
+	if infos {
+		if !outIsATTY {
+			fatal(cmd, "showing informations in non-TTY mode is not supported")
 		}
-		data, err := post.GetImageContent(ctx)
-		checkErr(err, cmd, "failed to fetch post image")
-		defer data.Close()
-		outputContentType = "application/octet-stream"
-		_, err = io.Copy(cmd.OutOrStdout(), data)
-		checkErr(err, cmd, "failed to fetch post image")
-	},
+		checkErr(cli.DisplayPostInfos(cmd.OutOrStdout(), post, false), cmd, "failed to display post informations")
+	}
+
+	if displayer != nil {
+		checkErr(cli.DisplayPostImage(cmd.Context(), cmd.OutOrStdout(), post, variant, displayer), cmd, "failed to display post")
+		return
+	}
+	data, err := post.GetImageContentVariant(ctx, variant)
+	checkErr(err, cmd, "failed to fetch post image")
+	defer data.Close()
+	outputContentType = data.ContentType
+	_, err = io.Copy(cmd.OutOrStdout(), data)
+	checkErr(err, cmd, "failed to fetch post image")
 }
 
 func init() {
+	showCmd.Flags().BoolVar(&showHires, "hires", false, "show the high resolution (2x) image, if the post has one")
 	showCmd.Flags().BoolVarP(&showInfos, "infos", "i", false, "show post informations")
 	rootCmd.AddCommand(showCmd)
 }
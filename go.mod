@@ -13,6 +13,7 @@ require (
 	github.com/samber/slog-mock v0.1.0
 	github.com/spf13/cobra v1.8.1
 	github.com/stretchr/testify v1.10.0
+	golang.org/x/image v0.24.0
 	golang.org/x/term v0.29.0
 	modernc.org/sqlite v1.35.0
 )
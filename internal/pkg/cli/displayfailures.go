@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/gosuri/uitable"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+// DisplayFailures displays the posts which failed to be indexed.
+func DisplayFailures(out io.Writer, failures []xkcdindex.FailedPost, jsonMode bool) error {
+	if jsonMode {
+		if failures == nil {
+			failures = []xkcdindex.FailedPost{}
+		}
+		b, err := json.MarshalIndent(failures, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		_, err = out.Write(b)
+		return err
+	}
+
+	if len(failures) == 0 {
+		fmt.Fprintln(out, "No failed post.")
+		return nil
+	}
+
+	table := uitable.New()
+	table.MaxColWidth = 80
+	table.Wrap = true // wrap columns
+
+	table.AddRow("#", "Class", "Attempts", "Last failed on", "Next retry on", "Error")
+	for _, f := range failures {
+		table.AddRow(
+			fmt.Sprintf("%d", f.Num),
+			color.YellowString(string(f.Class)),
+			fmt.Sprintf("%d", f.Attempts),
+			f.LastFailedAt.Format(time.DateTime),
+			f.NextRetryAt.Format(time.DateTime),
+			f.Error,
+		)
+	}
+
+	fmt.Fprintln(out, table)
+	return nil
+}
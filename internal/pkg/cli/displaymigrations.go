@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/fatih/color"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+// DisplayMigrations displays index schema migrations, either applied or pending.
+func DisplayMigrations(out io.Writer, migrations []xkcdindex.Migration, applied, jsonMode bool) error {
+	if jsonMode {
+		if migrations == nil {
+			migrations = []xkcdindex.Migration{}
+		}
+		b, err := json.MarshalIndent(migrations, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		_, err = out.Write(b)
+		return err
+	}
+
+	if len(migrations) == 0 {
+		fmt.Fprintf(out, "Index schema is up to date (version %d).\n", xkcdindex.SchemaVersion())
+		return nil
+	}
+	if applied {
+		fmt.Fprintln(out, "Applied migrations:")
+	} else {
+		fmt.Fprintln(out, "Pending migrations:")
+	}
+	for _, m := range migrations {
+		fmt.Fprintf(out, "  %s %s\n", color.CyanString("%3d", m.Version), m.Description)
+	}
+	return nil
+}
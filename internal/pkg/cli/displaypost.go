@@ -18,20 +18,73 @@ import (
 	"github.com/jucrouzet/xkcd/pkg/xkcd"
 )
 
-// DisplayPost displays a post image.
+// Maximum size of images displayed in the terminal, larger ones are scaled down.
+const (
+	maxDisplayWidth  = 1600
+	maxDisplayHeight = 2400
+)
+
+// DisplayPost displays a post image, in the given variant.
+// The scale of the image is decided from its dimensions before fetching it: images larger than the maximum
+// display size are scaled down, and displayed from Variant1x if it is enough.
 func DisplayPostImage(
 	ctx context.Context,
 	out io.Writer,
 	post *xkcd.Post,
+	variant xkcd.ImageVariant,
 	d Displayer,
 ) error {
-	img, _, err := post.GetImage(ctx)
+	// Only the dimensions are needed, which are read from the beginning of images, even GIF ones.
+	width, height, err := post.GetImageDimensionsVariant(ctx, variant)
+	if err != nil {
+		return fmt.Errorf("failed to get image dimensions: %w", err)
+	}
+	scale := displayScale(width, height)
+	if variant == xkcd.Variant2x && scale > 1 {
+		// The 2x image would be scaled down anyway, the 1x one is smaller to fetch and decode.
+		variant = xkcd.Variant1x
+		if width, height, err = post.GetImageDimensionsVariant(ctx, variant); err != nil {
+			return fmt.Errorf("failed to get image dimensions: %w", err)
+		}
+		scale = displayScale(width, height)
+	}
+	img, _, err := post.GetImageVariant(ctx, variant)
 	if err != nil {
 		return fmt.Errorf("failed to get image: %w", err)
 	}
+	if scale > 1 {
+		img = scaleDown(img, scale)
+	}
 	return d(out, img)
 }
 
+// displayScale returns the factor by which an image of the given size must be scaled down to be displayed.
+func displayScale(width, height int) int {
+	return max(1, (width+maxDisplayWidth-1)/maxDisplayWidth, (height+maxDisplayHeight-1)/maxDisplayHeight)
+}
+
+// scaleDown scales img down by factor, keeping a paletted image paletted.
+func scaleDown(img image.Image, factor int) image.Image {
+	b := img.Bounds()
+	rect := image.Rect(0, 0, b.Dx()/factor, b.Dy()/factor)
+	if paletted, ok := img.(*image.Paletted); ok {
+		scaled := image.NewPaletted(rect, paletted.Palette)
+		for y := range rect.Dy() {
+			for x := range rect.Dx() {
+				scaled.SetColorIndex(x, y, paletted.ColorIndexAt(b.Min.X+x*factor, b.Min.Y+y*factor))
+			}
+		}
+		return scaled
+	}
+	scaled := image.NewRGBA(rect)
+	for y := range rect.Dy() {
+		for x := range rect.Dx() {
+			scaled.Set(x, y, img.At(b.Min.X+x*factor, b.Min.Y+y*factor))
+		}
+	}
+	return scaled
+}
+
 // DisplayPostInfos displays the infos of a post.
 func DisplayPostInfos(out io.Writer, post *xkcd.Post, jsonMode bool) error {
 	if jsonMode {
@@ -75,14 +128,6 @@ func DisplayPostInfos(out io.Writer, post *xkcd.Post, jsonMode bool) error {
 			title: "Alt text",
 			value: post.Alt,
 		},
-		{
-			title: "Transcript",
-			value: post.Transcript,
-		},
-		{
-			title: "News",
-			value: post.News,
-		},
 	}
 	for _, v := range values {
 		if strings.TrimSpace(v.value) == "" {
@@ -93,11 +138,69 @@ func DisplayPostInfos(out io.Writer, post *xkcd.Post, jsonMode bool) error {
 			color.CyanString(v.value),
 		)
 	}
+	for k, line := range transcriptLines(post) {
+		title := ""
+		if k == 0 {
+			title = "Transcript:"
+		}
+		table.AddRow(title, line)
+	}
+	if strings.TrimSpace(post.News) != "" {
+		table.AddRow("News:", color.CyanString(post.News))
+	}
 
 	fmt.Fprintln(out, table)
 	return nil
 }
 
+// speakerColors are the colors of transcript speakers, in order of first appearance.
+var speakerColors = []*color.Color{
+	color.New(color.FgYellow, color.Bold),
+	color.New(color.FgGreen, color.Bold),
+	color.New(color.FgMagenta, color.Bold),
+	color.New(color.FgBlue, color.Bold),
+	color.New(color.FgRed, color.Bold),
+}
+
+// transcriptLines returns the lines of the post transcript, with colored speakers, panels separated by an empty
+// line, and without title text duplicating the post alt text.
+func transcriptLines(post *xkcd.Post) []string {
+	transcript := post.ParsedTranscript()
+	colors := map[string]*color.Color{}
+	for k, speaker := range transcript.Speakers() {
+		colors[speaker] = speakerColors[k%len(speakerColors)]
+	}
+	scene := color.New(color.Faint, color.Italic)
+	alt := strings.Join(strings.Fields(post.Alt), " ")
+
+	var lines []string
+	for _, panel := range transcript.Panels {
+		var panelLines []string
+		for _, elem := range panel.Elements {
+			switch elem.Kind {
+			case xkcd.TranscriptScene:
+				panelLines = append(panelLines, scene.Sprintf("[%s]", elem.Text))
+			case xkcd.TranscriptDialogue:
+				panelLines = append(panelLines, colors[elem.Speaker].Sprintf("%s:", elem.Speaker)+" "+color.CyanString(elem.Text))
+			case xkcd.TranscriptTitleText:
+				if strings.Join(strings.Fields(elem.Text), " ") != alt {
+					panelLines = append(panelLines, "Title text: "+color.CyanString(elem.Text))
+				}
+			default:
+				panelLines = append(panelLines, color.CyanString(elem.Text))
+			}
+		}
+		if len(panelLines) == 0 {
+			continue
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, panelLines...)
+	}
+	return lines
+}
+
 // Displayer is a function that writes an image to an output writer.
 type Displayer func(io.Writer, image.Image) error
 
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/gosuri/uitable"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+// DisplaySearchResults displays the results of a search.
+func DisplaySearchResults(out io.Writer, results []xkcdindex.SearchResult, jsonMode bool) error {
+	if jsonMode {
+		if results == nil {
+			results = []xkcdindex.SearchResult{}
+		}
+		b, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		_, err = out.Write(b)
+		return err
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintln(out, "No post found.")
+		return nil
+	}
+
+	table := uitable.New()
+	table.MaxColWidth = 80
+	table.Wrap = true // wrap columns
+
+	table.AddRow("#", "Title", "Published on", "Excerpt")
+	for _, result := range results {
+		table.AddRow(
+			fmt.Sprintf("%d", result.Post.Num),
+			color.CyanString(result.Post.Title),
+			result.Post.Date.Format(time.DateOnly),
+			highlight(result.Snippet),
+		)
+	}
+
+	fmt.Fprintln(out, table)
+	return nil
+}
+
+// highlight replaces snippet highlight markers with terminal colors.
+func highlight(snippet string) string {
+	snippet = strings.Join(strings.Fields(snippet), " ")
+	var b strings.Builder
+	for {
+		start := strings.Index(snippet, xkcdindex.HighlightStart)
+		if start < 0 {
+			break
+		}
+		end := strings.Index(snippet[start:], xkcdindex.HighlightEnd)
+		if end < 0 {
+			break
+		}
+		end += start
+		b.WriteString(snippet[:start])
+		b.WriteString(color.New(color.FgYellow, color.Bold).Sprint(snippet[start+len(xkcdindex.HighlightStart) : end]))
+		snippet = snippet[end+len(xkcdindex.HighlightEnd):]
+	}
+	b.WriteString(snippet)
+	return b.String()
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/gosuri/uitable"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+// maxDisplayedGaps is the maximum number of gaps listed by DisplayStats.
+const maxDisplayedGaps = 10
+
+// histogramWidth is the width of the longest bar of the posts per year histogram.
+const histogramWidth = 40
+
+// DisplayStats displays the statistics of the index.
+func DisplayStats(out io.Writer, stats *xkcdindex.Stats, jsonMode bool) error {
+	if jsonMode {
+		b, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		_, err = out.Write(b)
+		return err
+	}
+
+	table := uitable.New()
+	table.MaxColWidth = 80
+	table.Wrap = true // wrap columns
+
+	lastUpdate := "never"
+	if stats.LastUpdate != nil {
+		lastUpdate = stats.LastUpdate.Format(time.DateTime)
+	}
+	postRange := "none"
+	if stats.Posts > 0 {
+		postRange = fmt.Sprintf("#%d to #%d", stats.FirstNum, stats.LastNum)
+	}
+	table.AddRow("Posts:", color.CyanString("%d", stats.Posts))
+	table.AddRow("Range:", color.CyanString(postRange))
+	table.AddRow("Gaps:", color.CyanString(formatGaps(stats.Gaps)))
+	table.AddRow("With transcript:", color.CyanString("%d", stats.WithTranscript))
+	table.AddRow("Without transcript:", color.CyanString("%d", stats.WithoutTranscript))
+	if stats.Offline {
+		table.AddRow("Offline images:", color.CyanString("%d (%s)", stats.OfflineImages, formatSize(stats.OfflineImagesSize)))
+	} else {
+		table.AddRow("Offline images:", color.CyanString("disabled"))
+	}
+	table.AddRow("Index file size:", color.CyanString(formatSize(stats.FileSize)))
+	table.AddRow("Last update:", color.CyanString(lastUpdate))
+	fmt.Fprintln(out, table)
+
+	if len(stats.PostsPerYear) == 0 {
+		return nil
+	}
+	fmt.Fprintln(out, "\nPosts per year:")
+	highest := uint(0)
+	for _, y := range stats.PostsPerYear {
+		highest = max(highest, y.Posts)
+	}
+	for _, y := range stats.PostsPerYear {
+		bar := strings.Repeat("█", int(y.Posts*histogramWidth/highest))
+		fmt.Fprintf(out, "  %d %s %d\n", y.Year, color.CyanString(bar), y.Posts)
+	}
+	return nil
+}
+
+func formatGaps(gaps []uint) string {
+	if len(gaps) == 0 {
+		return "none"
+	}
+	nums := make([]string, 0, maxDisplayedGaps)
+	for _, num := range gaps[:min(len(gaps), maxDisplayedGaps)] {
+		nums = append(nums, fmt.Sprintf("#%d", num))
+	}
+	if len(gaps) > maxDisplayedGaps {
+		nums = append(nums, "…")
+	}
+	return fmt.Sprintf("%d (%s)", len(gaps), strings.Join(nums, ", "))
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,22 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// DisplaySummary displays the summary of a command result, either as text or, in JSON mode, as the JSON
+// encoding of summary.
+func DisplaySummary(out io.Writer, summary any, text string, jsonMode bool) error {
+	if jsonMode {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(summary); err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		return nil
+	}
+	_, err := fmt.Fprintln(out, text)
+	return err
+}
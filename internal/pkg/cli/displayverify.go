@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/fatih/color"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+type verifyReport struct {
+	Problems []xkcdindex.Problem `json:"problems"`
+	Fixed    []xkcdindex.Problem `json:"fixed,omitempty"`
+}
+
+// DisplayVerifyReport displays the problems found while verifying the index, and the ones fixed if repair was requested.
+func DisplayVerifyReport(out io.Writer, problems, fixed []xkcdindex.Problem, repair, jsonMode bool) error {
+	if jsonMode {
+		report := verifyReport{Problems: problems, Fixed: fixed}
+		if report.Problems == nil {
+			report.Problems = []xkcdindex.Problem{}
+		}
+		if repair && report.Fixed == nil {
+			report.Fixed = []xkcdindex.Problem{}
+		}
+		b, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		_, err = out.Write(b)
+		return err
+	}
+
+	if len(problems) == 0 {
+		fmt.Fprintln(out, "Index is valid.")
+		return nil
+	}
+	fmt.Fprintf(out, "Found %d problems:\n", len(problems))
+	for _, p := range problems {
+		displayProblem(out, p)
+	}
+	if !repair {
+		return nil
+	}
+	if len(fixed) == 0 {
+		fmt.Fprintln(out, "No problem was fixed.")
+		return nil
+	}
+	fmt.Fprintf(out, "Fixed %d problems:\n", len(fixed))
+	for _, p := range fixed {
+		displayProblem(out, p)
+	}
+	return nil
+}
+
+func displayProblem(out io.Writer, p xkcdindex.Problem) {
+	num := "     "
+	if p.Num > 0 {
+		num = color.CyanString("#%-4d", p.Num)
+	}
+	fmt.Fprintf(out, "  %s %s %s\n", num, color.YellowString("%-14s", p.Kind), p.Message)
+}
@@ -0,0 +1,275 @@
+package xkcd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// heuristicMaxFreshness is the maximum freshness lifetime given to responses without explicit expiration.
+const heuristicMaxFreshness = 24 * time.Hour
+
+// CacheEntry is a HTTP response stored in a Cache.
+type CacheEntry struct {
+	// Header is the header of the response.
+	Header http.Header `json:"header"`
+	// Body is the body of the response.
+	Body []byte `json:"body"`
+	// StoredAt is the date when the response was received or last revalidated.
+	StoredAt time.Time `json:"stored_at"`
+}
+
+// Cache stores responses of the API and image servers, keyed by URL.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the entry stored for key, or nil if there is none.
+	Get(ctx context.Context, key string) (*CacheEntry, error)
+	// Set stores the entry for key, replacing any existing one.
+	Set(ctx context.Context, key string, entry *CacheEntry) error
+}
+
+// MemoryCache is a Cache storing responses in memory, without eviction.
+type MemoryCache struct {
+	entries map[string]*CacheEntry
+	mu      sync.RWMutex
+}
+
+// NewMemoryCache returns a new empty in-memory cache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: map[string]*CacheEntry{}}
+}
+
+// Get implements Cache.
+func (m *MemoryCache) Get(_ context.Context, key string) (*CacheEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.entries[key], nil
+}
+
+// Set implements Cache.
+func (m *MemoryCache) Set(_ context.Context, key string, entry *CacheEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = entry
+	return nil
+}
+
+// DirCache is a Cache storing responses as files in a directory.
+type DirCache struct {
+	dir string
+}
+
+// NewDirCache returns a cache storing responses in dir, which is created if it does not exist.
+func NewDirCache(dir string) (*DirCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &DirCache{dir: dir}, nil
+}
+
+func (d *DirCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get implements Cache.
+func (d *DirCache) Get(_ context.Context, key string) (*CacheEntry, error) {
+	data, err := os.ReadFile(d.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to decode cache entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// Set implements Cache.
+func (d *DirCache) Set(_ context.Context, key string, entry *CacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	// Written to a temporary file first, so that concurrent readers never see a partial entry.
+	f, err := os.CreateTemp(d.dir, ".entry-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	_, err = f.Write(data)
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), d.path(key))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// cacheControl returns the directives of a Cache-Control header, with their value if any.
+func cacheControl(header http.Header) map[string]string {
+	directives := map[string]string{}
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			name, val, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name != "" {
+				directives[strings.ToLower(name)] = strings.Trim(val, `"`)
+			}
+		}
+	}
+	return directives
+}
+
+// fresh returns true if the entry can be used without revalidation at now, following RFC 9111 section 4.2.
+func (e *CacheEntry) fresh(now time.Time) bool {
+	cc := cacheControl(e.Header)
+	if _, ok := cc["no-cache"]; ok {
+		return false
+	}
+	return e.age(now) < e.lifetime(cc)
+}
+
+// lifetime returns the freshness lifetime of the entry.
+func (e *CacheEntry) lifetime(cc map[string]string) time.Duration {
+	if v, ok := cc["max-age"]; ok {
+		if seconds, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+		return 0
+	}
+	date := e.StoredAt
+	if d, err := http.ParseTime(e.Header.Get("Date")); err == nil {
+		date = d
+	}
+	if v := e.Header.Get("Expires"); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			return 0
+		}
+		return expires.Sub(date)
+	}
+	// Heuristic freshness, a fraction of the time since the last modification.
+	if lastModified, err := http.ParseTime(e.Header.Get("Last-Modified")); err == nil && lastModified.Before(date) {
+		return min(date.Sub(lastModified)/10, heuristicMaxFreshness)
+	}
+	return 0
+}
+
+// age returns the age of the entry at now.
+func (e *CacheEntry) age(now time.Time) time.Duration {
+	age := now.Sub(e.StoredAt)
+	if seconds, err := strconv.ParseInt(e.Header.Get("Age"), 10, 64); err == nil && seconds > 0 {
+		age += time.Duration(seconds) * time.Second
+	}
+	return age
+}
+
+// response returns a response serving the entry.
+func (e *CacheEntry) response() *http.Response {
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+	}
+}
+
+// cacheable returns true if a response can be stored.
+func cacheable(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	_, noStore := cacheControl(resp.Header)["no-store"]
+	return !noStore
+}
+
+// cachedDo is do for GET requests, serving fresh responses from the cache of c, revalidating stale ones with conditional
+// requests, and storing cacheable responses, which must not be longer than maxBytes if it is positive.
+func (c *Client) cachedDo(
+	ctx context.Context,
+	client HTTPClient,
+	rawURL string,
+	maxBytes int64,
+	logger *slog.Logger,
+) (*http.Response, error) {
+	if err := c.checkHost(rawURL); err != nil {
+		return nil, err
+	}
+	if c.cache == nil {
+		return c.do(ctx, client, http.MethodGet, rawURL, nil, logger)
+	}
+	entry, err := c.cache.Get(ctx, rawURL)
+	if err != nil {
+		logger.Warn("failed to get response from cache", slog.String("error", err.Error()))
+		entry = nil
+	}
+	var header http.Header
+	if entry != nil {
+		if entry.fresh(time.Now()) {
+			logger.Debug("serving response from cache")
+			return entry.response(), nil
+		}
+		header = http.Header{}
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			header.Set("If-None-Match", etag)
+		}
+		if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+			header.Set("If-Modified-Since", lastModified)
+		}
+	}
+	resp, err := c.do(ctx, client, http.MethodGet, rawURL, header, logger)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		logger.Debug("cached response revalidated")
+		// Headers of a 304 response update the stored ones, see RFC 9111 section 4.3.4.
+		for name, values := range resp.Header {
+			if name != "Content-Length" {
+				entry.Header[name] = values
+			}
+		}
+		entry.StoredAt = time.Now()
+		c.storeEntry(ctx, rawURL, entry, logger)
+		return entry.response(), nil
+	}
+	if !cacheable(resp) {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(limitReader(rawURL, resp.Body, maxBytes))
+	if err != nil {
+		return nil, newResponseError(rawURL, resp, "failed to read response", err)
+	}
+	entry = &CacheEntry{Header: resp.Header, Body: body, StoredAt: time.Now()}
+	c.storeEntry(ctx, rawURL, entry, logger)
+	return entry.response(), nil
+}
+
+func (c *Client) storeEntry(ctx context.Context, key string, entry *CacheEntry, logger *slog.Logger) {
+	if err := c.cache.Set(ctx, key, entry); err != nil {
+		logger.Warn("failed to store response in cache", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,127 @@
+package xkcd_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+type cacheTestServer struct {
+	*httptest.Server
+	requests []*http.Request
+}
+
+// newCacheTestServer returns a server serving a valid post with the given headers, and answering
+// conditional requests matching them with 304 Not Modified.
+func newCacheTestServer(t *testing.T, header map[string]string) *cacheTestServer {
+	t.Helper()
+	srv := &cacheTestServer{}
+	srv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		srv.requests = append(srv.requests, r)
+		for name, value := range header {
+			w.Header().Set(name, value)
+		}
+		etag := header["ETag"]
+		lastModified := header["Last-Modified"]
+		if (etag != "" && r.Header.Get("If-None-Match") == etag) ||
+			(lastModified != "" && r.Header.Get("If-Modified-Since") == lastModified) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		resp, _ := sendValidPost(t)
+		_, _ = io.Copy(w, resp.Body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWithCache(t *testing.T) {
+	ctx := context.Background()
+
+	getTwice := func(t *testing.T, c *xkcd.Client) {
+		t.Helper()
+		for range 2 {
+			p, err := c.GetLatest(ctx)
+			require.NoError(t, err, "expected no error")
+			assert.Equal(t, "Barrel - Part 1", p.Title, "expected post to be served")
+		}
+	}
+
+	t.Run("fresh responses are served from cache", func(t *testing.T) {
+		srv := newCacheTestServer(t, map[string]string{"Cache-Control": "max-age=300"})
+		getTwice(t, xkcd.New(allowLoopback(), xkcd.WithBaseURL(srv.URL), xkcd.WithCache(xkcd.NewMemoryCache())))
+		assert.Len(t, srv.requests, 1, "expected a single request")
+	})
+
+	t.Run("stale responses are revalidated with ETag", func(t *testing.T) {
+		srv := newCacheTestServer(t, map[string]string{"Cache-Control": "no-cache", "ETag": `"v1"`})
+		getTwice(t, xkcd.New(allowLoopback(), xkcd.WithBaseURL(srv.URL), xkcd.WithCache(xkcd.NewMemoryCache())))
+		require.Len(t, srv.requests, 2, "expected response to be revalidated")
+		assert.Equal(t, `"v1"`, srv.requests[1].Header.Get("If-None-Match"), "expected conditional request")
+	})
+
+	t.Run("stale responses are revalidated with Last-Modified", func(t *testing.T) {
+		now := time.Now().UTC()
+		srv := newCacheTestServer(t, map[string]string{
+			"Expires":       now.Add(-time.Minute).Format(http.TimeFormat),
+			"Last-Modified": now.Add(-time.Hour).Format(http.TimeFormat),
+		})
+		getTwice(t, xkcd.New(allowLoopback(), xkcd.WithBaseURL(srv.URL), xkcd.WithCache(xkcd.NewMemoryCache())))
+		require.Len(t, srv.requests, 2, "expected response to be revalidated")
+		assert.Equal(t, now.Add(-time.Hour).Format(http.TimeFormat), srv.requests[1].Header.Get("If-Modified-Since"), "expected conditional request")
+	})
+
+	t.Run("heuristic freshness", func(t *testing.T) {
+		srv := newCacheTestServer(t, map[string]string{
+			"Last-Modified": time.Now().Add(-10 * time.Hour).UTC().Format(http.TimeFormat),
+		})
+		getTwice(t, xkcd.New(allowLoopback(), xkcd.WithBaseURL(srv.URL), xkcd.WithCache(xkcd.NewMemoryCache())))
+		assert.Len(t, srv.requests, 1, "expected response to be fresh for a tenth of its age")
+	})
+
+	t.Run("no-store responses are not cached", func(t *testing.T) {
+		srv := newCacheTestServer(t, map[string]string{"Cache-Control": "no-store, max-age=300"})
+		getTwice(t, xkcd.New(allowLoopback(), xkcd.WithBaseURL(srv.URL), xkcd.WithCache(xkcd.NewMemoryCache())))
+		assert.Len(t, srv.requests, 2, "expected response not to be cached")
+	})
+
+	t.Run("directory cache", func(t *testing.T) {
+		srv := newCacheTestServer(t, map[string]string{"Cache-Control": "max-age=300"})
+		dir := t.TempDir()
+		for range 2 {
+			cache, err := xkcd.NewDirCache(dir)
+			require.NoError(t, err, "expected no error")
+			getTwice(t, xkcd.New(allowLoopback(), xkcd.WithBaseURL(srv.URL), xkcd.WithCache(cache)))
+		}
+		assert.Len(t, srv.requests, 1, "expected cache to be persisted")
+	})
+
+	t.Run("images", func(t *testing.T) {
+		imageRequests := 0
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			if r.URL.Host != "imgs.xkcd.com" {
+				return sendValidPost(t)
+			}
+			imageRequests++
+			resp := getImageResponse(t, "png", nil, nil)
+			resp.Header.Set("Cache-Control", "max-age=3600")
+			return resp, nil
+		}, nil, xkcd.WithCache(xkcd.NewMemoryCache()))
+		p, err := c.GetLatest(ctx)
+		require.NoError(t, err, "expected no error")
+		for range 2 {
+			_, format, err := p.GetImage(ctx)
+			assert.NoError(t, err, "expected no error")
+			assert.Equal(t, "png", format, "expected image to be decoded")
+		}
+		assert.Equal(t, 1, imageRequests, "expected image to be cached")
+	})
+}
@@ -8,6 +8,11 @@ type HTTPClient interface {
 	Do(*http.Request) (*http.Response, error)
 }
 
+// HTTPClient returns the http client used by the client when none is given to its methods.
+func (c *Client) HTTPClient() HTTPClient {
+	return c.defaultClient
+}
+
 func (c *Client) getClient(given ...HTTPClient) HTTPClient {
 	if len(given) > 0 {
 		return given[0]
@@ -0,0 +1,90 @@
+package xkcd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// DailyPick picks deterministically one of nums for the calendar day of date, in its location, and seed.
+// Everyone using the same seed, date and numbers gets the same pick, and a different one the next day.
+// It returns zero if nums is empty.
+func DailyPick(seed string, date time.Time, nums []uint) uint {
+	if len(nums) == 0 {
+		return 0
+	}
+	sum := sha256.Sum256([]byte(seed + "\n" + date.Format(time.DateOnly)))
+	return nums[binary.BigEndian.Uint64(sum[:8])%uint64(len(nums))]
+}
+
+// DailyPickEnd returns the end of the calendar day of date, in its location, which DailyPick picks are published
+// before. Post dates are calendar days at midnight in time.Local, so it is the midnight in time.Local following
+// that calendar day, whatever the location of date.
+func DailyPickEnd(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day()+1, 0, 0, 0, 0, time.Local)
+}
+
+// GetDailyPick retrieves the post picked by DailyPick for date and seed, among all posts published on or before
+// the calendar day of date, see DailyPickEnd, except posts which were never published like #404.
+func (c *Client) GetDailyPick(ctx context.Context, seed string, date time.Time, client ...HTTPClient) (*Post, error) {
+	latest, err := c.GetLatest(ctx, client...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest post: %w", err)
+	}
+	end := DailyPickEnd(date)
+	last := latest.Num
+	if !latest.Date.Before(end) {
+		last, err = c.lastPublishedBefore(ctx, end, latest.Num, client...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if last == 0 {
+		return nil, fmt.Errorf("%w: no post published on or before %s", ErrNoSuchPost, date.Format(time.DateOnly))
+	}
+	nums := make([]uint, 0, last)
+	for num := uint(1); num <= last; num++ {
+		if !knownMissingPosts[num] {
+			nums = append(nums, num)
+		}
+	}
+	num := DailyPick(seed, date, nums)
+	if num == latest.Num {
+		return latest, nil
+	}
+	return c.GetPost(ctx, num, client...)
+}
+
+// lastPublishedBefore returns the number of the last post published before end, searching posts up to
+// the post before, which is known to be published after end. It returns zero if there is none.
+func (c *Client) lastPublishedBefore(ctx context.Context, end time.Time, before uint, client ...HTTPClient) (uint, error) {
+	// Post lo is published before end, or zero, and post hi is not.
+	lo, hi := uint(0), before
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		for mid > lo && knownMissingPosts[mid] {
+			mid--
+		}
+		if mid == lo {
+			mid = lo + (hi-lo)/2
+			for mid < hi && knownMissingPosts[mid] {
+				mid++
+			}
+			if mid == hi {
+				break
+			}
+		}
+		post, err := c.GetPost(ctx, mid, client...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get post %d: %w", mid, err)
+		}
+		if post.Date.Before(end) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
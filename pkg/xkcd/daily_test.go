@@ -0,0 +1,91 @@
+package xkcd_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+// sendDailyPost sends post num, published num days after 2006-01-01, with 500 as the latest post.
+func sendDailyPost(t testing.TB, r *http.Request) (*http.Response, error) {
+	t.Helper()
+	num := 500
+	if r.URL.Path != "/info.0.json" {
+		var err error
+		num, err = strconv.Atoi(strings.Split(strings.Trim(r.URL.Path, "/"), "/")[0])
+		require.NoError(t, err, "expected a post number in request path")
+		assert.NotEqual(t, 404, num, "expected #404 not to be requested")
+	}
+	date := time.Date(2006, time.January, 1+num, 0, 0, 0, 0, time.Local)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body: io.NopCloser(strings.NewReader(fmt.Sprintf(
+			`{"month": "%d", "num": %d, "link": "", "year": "%d", "news": "", "safe_title": "Post", "transcript": "", "alt": "Alt", "img": "https://imgs.xkcd.com/comics/post.png", "title": "Post", "day": "%d"}`,
+			date.Month(), num, date.Year(), date.Day(),
+		))),
+	}, nil
+}
+
+func TestDailyPick(t *testing.T) {
+	nums := []uint{1, 2, 3, 5, 8, 13, 21, 34, 55, 89}
+	date := time.Date(2024, time.March, 1, 8, 30, 0, 0, time.Local)
+
+	picked := xkcd.DailyPick("team", date, nums)
+	assert.Contains(t, nums, picked, "expected one of the numbers to be picked")
+	assert.Equal(t, picked, xkcd.DailyPick("team", date.Add(12*time.Hour), nums), "expected the same pick during the day")
+	assert.Equal(t, uint(0), xkcd.DailyPick("team", date, nil), "expected zero without numbers")
+
+	days := map[uint]bool{}
+	seeds := map[uint]bool{}
+	for k := range 30 {
+		days[xkcd.DailyPick("team", date.AddDate(0, 0, k), nums)] = true
+		seeds[xkcd.DailyPick(fmt.Sprintf("team-%d", k), date, nums)] = true
+	}
+	assert.Greater(t, len(days), 1, "expected picks to change with days")
+	assert.Greater(t, len(seeds), 1, "expected picks to change with seeds")
+}
+
+func TestClient_GetDailyPick(t *testing.T) {
+	ctx := context.Background()
+	c := getClient(t, func(r *http.Request) (*http.Response, error) {
+		return sendDailyPost(t, r)
+	}, nil)
+
+	t.Run("posts newer than date are excluded", func(t *testing.T) {
+		for k := range 20 {
+			date := time.Date(2006, time.January, 1+400+k, 9, 0, 0, 0, time.Local)
+			post, err := c.GetDailyPick(ctx, "team", date)
+			require.NoError(t, err, "expected no error")
+			var nums []uint
+			for num := uint(1); num <= uint(400+k); num++ {
+				if num != 404 {
+					nums = append(nums, num)
+				}
+			}
+			assert.Equal(t, xkcd.DailyPick("team", date, nums), post.Num, "expected post to be picked among older posts")
+		}
+	})
+
+	t.Run("date after the latest post", func(t *testing.T) {
+		date := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.Local)
+		post, err := c.GetDailyPick(ctx, "team", date)
+		require.NoError(t, err, "expected no error")
+		assert.LessOrEqual(t, post.Num, uint(500), "expected an existing post")
+		assert.NotEqual(t, uint(404), post.Num, "expected #404 not to be picked")
+	})
+
+	t.Run("date before the first post", func(t *testing.T) {
+		_, err := c.GetDailyPick(ctx, "team", time.Date(2006, time.January, 1, 0, 0, 0, 0, time.Local))
+		assert.ErrorIs(t, err, xkcd.ErrNoSuchPost, "expected no such post error")
+	})
+}
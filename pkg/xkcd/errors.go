@@ -0,0 +1,119 @@
+package xkcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// APIError is an error returned by xkcd API or image servers, or while sending them a request.
+// It matches ErrAPIError with errors.Is, except for posts which do not exist, which match ErrNoSuchPost.
+type APIError struct {
+	// URL is the requested URL.
+	URL string `json:"url"`
+	// StatusCode is the HTTP status code of the response, zero if no response was received.
+	StatusCode int `json:"status_code,omitempty"`
+	// ContentType is the content type of the response, empty if no response was received.
+	ContentType string `json:"content_type,omitempty"`
+	// Message describes the error.
+	Message string `json:"message"`
+	// Err is the underlying error, if any.
+	Err error `json:"-"`
+}
+
+// newResponseError returns an APIError for the response resp to a request to rawURL.
+func newResponseError(rawURL string, resp *http.Response, message string, err error) *APIError {
+	return &APIError{
+		URL:         rawURL,
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get(contentTypeHeader),
+		Message:     message,
+		Err:         err,
+	}
+}
+
+// Error implements error.
+func (e *APIError) Error() string {
+	msg := e.Message
+	if e.Err != nil {
+		if msg != "" {
+			msg += ": "
+		}
+		msg += e.Err.Error()
+	}
+	if e.notFound() {
+		return msg
+	}
+	return ErrAPIError.Error() + ": " + msg
+}
+
+// Is makes APIError match ErrAPIError, except for posts which do not exist.
+func (e *APIError) Is(target error) bool {
+	return target == ErrAPIError && !e.notFound()
+}
+
+// Unwrap returns the underlying error.
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+func (e *APIError) notFound() bool {
+	return errors.Is(e.Err, ErrNoSuchPost)
+}
+
+// Temporary returns true if the error is expected to resolve by itself, such as a timeout, an overloaded
+// server or rate limiting.
+func (e *APIError) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case 0:
+		var netErr net.Error
+		return errors.Is(e.Err, context.DeadlineExceeded) || errors.As(e.Err, &netErr) && netErr.Timeout()
+	}
+	return false
+}
+
+// Retryable returns true if sending the request again may succeed: the error is temporary, is a server
+// error, or is a failure to send the request which was not canceled nor denied by ErrAddressNotAllowed or
+// ErrHostNotAllowed.
+func (e *APIError) Retryable() bool {
+	if e.Temporary() || e.StatusCode == http.StatusInternalServerError {
+		return true
+	}
+	return e.StatusCode == 0 &&
+		e.Err != nil &&
+		!errors.Is(e.Err, context.Canceled) &&
+		!errors.Is(e.Err, ErrAddressNotAllowed) &&
+		!errors.Is(e.Err, ErrHostNotAllowed)
+}
+
+// ImageTooLargeError is returned when an image exceeds the limits set by WithMaxImageBytes or WithMaxImagePixels.
+// It matches ErrImageTooLarge with errors.Is.
+type ImageTooLargeError struct {
+	// URL is the image URL.
+	URL string `json:"url"`
+	// MaxBytes is the maximum length of the image content in bytes, if it was exceeded.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+	// MaxPixels is the maximum number of pixels of the image, if it was exceeded.
+	MaxPixels int64 `json:"max_pixels,omitempty"`
+	// Width is the width of the image in pixels, if MaxPixels was exceeded.
+	Width int `json:"width,omitempty"`
+	// Height is the height of the image in pixels, if MaxPixels was exceeded.
+	Height int `json:"height,omitempty"`
+}
+
+// Error implements error.
+func (e *ImageTooLargeError) Error() string {
+	if e.MaxPixels > 0 {
+		return fmt.Sprintf("%v: %dx%d exceeds %d pixels", ErrImageTooLarge, e.Width, e.Height, e.MaxPixels)
+	}
+	return fmt.Sprintf("%v: content exceeds %d bytes", ErrImageTooLarge, e.MaxBytes)
+}
+
+// Is makes ImageTooLargeError match ErrImageTooLarge.
+func (e *ImageTooLargeError) Is(target error) bool {
+	return target == ErrImageTooLarge
+}
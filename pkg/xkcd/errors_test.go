@@ -0,0 +1,103 @@
+package xkcd_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestAPIError(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("error status", func(t *testing.T) {
+		c := getClient(t, func(_ *http.Request) (*http.Response, error) {
+			return statusResponse(http.StatusServiceUnavailable, "Content-Type", "text/html"), nil
+		}, nil)
+		_, err := c.GetPost(ctx, 12)
+		assert.ErrorIs(t, err, xkcd.ErrAPIError, "expected API error")
+		assert.EqualError(t, err, "xkcd API error: status code is 503", "expected error message")
+		var apiErr *xkcd.APIError
+		require.ErrorAs(t, err, &apiErr, "expected an *APIError")
+		assert.Equal(t, "https://xkcd.com/12/info.0.json", apiErr.URL, "expected requested URL")
+		assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode, "expected status code")
+		assert.Equal(t, "text/html", apiErr.ContentType, "expected content type")
+		assert.True(t, apiErr.Temporary(), "expected error to be temporary")
+		assert.True(t, apiErr.Retryable(), "expected error to be retryable")
+	})
+
+	t.Run("post not found", func(t *testing.T) {
+		c := getClient(t, func(_ *http.Request) (*http.Response, error) {
+			return statusResponse(http.StatusNotFound), nil
+		}, nil)
+		_, err := c.GetPost(ctx, 404)
+		assert.ErrorIs(t, err, xkcd.ErrNoSuchPost, "expected no such post error")
+		assert.NotErrorIs(t, err, xkcd.ErrAPIError, "expected no API error")
+		assert.EqualError(t, err, "no such post", "expected error message")
+		var apiErr *xkcd.APIError
+		require.ErrorAs(t, err, &apiErr, "expected an *APIError")
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode, "expected status code")
+		assert.False(t, apiErr.Retryable(), "expected error not to be retryable")
+	})
+
+	t.Run("invalid image content type", func(t *testing.T) {
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			if r.URL.Host == "imgs.xkcd.com" {
+				return statusResponse(http.StatusOK, "Content-Type", "text/plain"), nil
+			}
+			return sendValidPost(t)
+		}, nil)
+		p, err := c.GetPost(ctx, 1)
+		require.NoError(t, err, "expected no error")
+		_, err = p.GetImageContent(ctx)
+		var apiErr *xkcd.APIError
+		require.ErrorAs(t, err, &apiErr, "expected an *APIError")
+		assert.Equal(t, "https://imgs.xkcd.com/comics/barrel_cropped_(1).jpg", apiErr.URL, "expected image URL")
+		assert.Equal(t, "text/plain", apiErr.ContentType, "expected content type")
+		assert.False(t, apiErr.Retryable(), "expected error not to be retryable")
+	})
+
+	t.Run("request failure", func(t *testing.T) {
+		c := getClient(t, func(_ *http.Request) (*http.Response, error) {
+			return nil, timeoutError{}
+		}, nil)
+		_, err := c.GetLatest(ctx)
+		assert.ErrorIs(t, err, xkcd.ErrAPIError, "expected API error")
+		var apiErr *xkcd.APIError
+		require.ErrorAs(t, err, &apiErr, "expected an *APIError")
+		assert.Zero(t, apiErr.StatusCode, "expected no status code")
+		assert.True(t, apiErr.Temporary(), "expected timeout to be temporary")
+		assert.True(t, apiErr.Retryable(), "expected timeout to be retryable")
+	})
+
+	cases := []struct {
+		name      string
+		err       *xkcd.APIError
+		temporary bool
+		retryable bool
+	}{
+		{name: "rate limited", err: &xkcd.APIError{StatusCode: http.StatusTooManyRequests}, temporary: true, retryable: true},
+		{name: "server error", err: &xkcd.APIError{StatusCode: http.StatusInternalServerError}, retryable: true},
+		{name: "forbidden", err: &xkcd.APIError{StatusCode: http.StatusForbidden}},
+		{name: "connection refused", err: &xkcd.APIError{Err: errors.New("connection refused")}, retryable: true},
+		{name: "deadline exceeded", err: &xkcd.APIError{Err: context.DeadlineExceeded}, temporary: true, retryable: true},
+		{name: "canceled", err: &xkcd.APIError{Err: context.Canceled}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.temporary, c.err.Temporary(), "expected temporary to be %v", c.temporary)
+			assert.Equal(t, c.retryable, c.err.Retryable(), "expected retryable to be %v", c.retryable)
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package xkcd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	// ErrHostNotAllowed is returned when a post or a request URL host is not allowed, see WithAllowedHosts.
+	ErrHostNotAllowed = errors.New("host is not allowed")
+	// ErrAddressNotAllowed is returned when a NewHTTPClient http client, such as the default one of clients,
+	// is denied a connection to a loopback, private or link-local address.
+	ErrAddressNotAllowed = errors.New("address is not allowed")
+)
+
+// maxRedirects is the maximum number of redirects followed by NewHTTPClient http clients.
+const maxRedirects = 10
+
+// DefaultAllowedHosts returns the hosts that clients are allowed to request by default, besides their mirrors.
+func DefaultAllowedHosts() []string {
+	return []string{"xkcd.com", "imgs.xkcd.com"}
+}
+
+// checkHost returns an error wrapping ErrHostNotAllowed if rawURL host is neither an allowed host of c, a
+// subdomain of one, nor the host of a mirror of c.
+// Clients which were not created by New, such as the one of posts not retrieved by a client, allow
+// DefaultAllowedHosts.
+func (c *Client) checkHost(rawURL string) error {
+	allowedHosts := c.allowedHosts
+	if allowedHosts == nil {
+		allowedHosts = DefaultAllowedHosts()
+	}
+	return checkURLHost(rawURL, allowedHosts, c.mirrorHosts())
+}
+
+// checkURLHost returns an error wrapping ErrHostNotAllowed if rawURL host is neither one of allowedHosts, a
+// subdomain of one, nor one of the exact hosts.
+func checkURLHost(rawURL string, allowedHosts []string, exact []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return nil
+		}
+	}
+	if slices.Contains(exact, host) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrHostNotAllowed, u.Hostname())
+}
+
+// mirrorHosts returns the hosts of the API and image base URLs of the mirrors of c, lower cased.
+func (c *Client) mirrorHosts() []string {
+	var hosts []string
+	for _, mirror := range c.mirrors() {
+		for _, base := range []string{mirror.BaseURL, mirror.ImageBaseURL} {
+			if u, err := url.Parse(base); err == nil && u.Hostname() != "" {
+				hosts = append(hosts, strings.ToLower(u.Hostname()))
+			}
+		}
+	}
+	return hosts
+}
+
+// checkPostHosts returns an error wrapping ErrHostNotAllowed if the image or link of post are not allowed by c.
+func (c *Client) checkPostHosts(post *Post) error {
+	if err := c.checkHost(post.Img); err != nil {
+		return fmt.Errorf("post image URL is invalid: %w", err)
+	}
+	if err := c.checkHost(post.Link); err != nil {
+		return fmt.Errorf("post link URL is invalid: %w", err)
+	}
+	return nil
+}
+
+// NewHTTPClient returns a http client refusing to connect to loopback, private and link-local addresses,
+// unless they are in one of the allowed networks, with an error wrapping ErrAddressNotAllowed.
+// Addresses are checked once resolved, when dialing, so hosts resolving to them are denied too.
+// Redirects are only followed to allowedHosts or their subdomains, others fail with ErrHostNotAllowed, and
+// allowedHosts defaults to DefaultAllowedHosts if nil.
+// It does not use proxies, not even those set by the HTTP_PROXY and HTTPS_PROXY environment variables, as only
+// the address of the proxy would then be checked. Use WithClient with a http client of your own to use a proxy.
+func NewHTTPClient(allowedHosts []string, allowedNetworks ...netip.Prefix) *http.Client {
+	if allowedHosts == nil {
+		allowedHosts = DefaultAllowedHosts()
+	}
+	return newHTTPClient(func(rawURL string) error {
+		return checkURLHost(rawURL, allowedHosts, nil)
+	}, allowedNetworks)
+}
+
+// newHTTPClient returns a NewHTTPClient http client, following redirects only to URLs allowed by checkHost.
+func newHTTPClient(checkHost func(rawURL string) error, allowedNetworks []netip.Prefix) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			return checkDialAddress(address, allowedNetworks)
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// The dialer only sees the address of proxies, not the one of the requested host.
+	transport.Proxy = nil
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			// The same limit as the default policy of http clients.
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return checkHost(req.URL.String())
+		},
+	}
+}
+
+// checkDialAddress returns an error wrapping ErrAddressNotAllowed if address is a loopback, private or link-local
+// address which is not in one of the allowed networks.
+func checkDialAddress(address string, allowedNetworks []netip.Prefix) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid dial address: %w", err)
+	}
+	addr := addrPort.Addr().Unmap()
+	if !isInternalAddr(addr) {
+		return nil
+	}
+	for _, prefix := range allowedNetworks {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrAddressNotAllowed, addr)
+}
+
+// isInternalAddr returns true if addr is an unspecified, loopback, private or link-local address.
+func isInternalAddr(addr netip.Addr) bool {
+	return addr.IsUnspecified() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast()
+}
@@ -0,0 +1,262 @@
+package xkcd_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+// sendPostWithURLs returns a mock client serving a post with the given image and link URLs, and a 1x1 image.
+func sendPostWithURLs(t testing.TB, img, link string, requested *[]string) mockClient {
+	t.Helper()
+	return func(r *http.Request) (*http.Response, error) {
+		*requested = append(*requested, r.URL.Host)
+		if strings.HasSuffix(r.URL.Path, "info.0.json") {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body: io.NopCloser(strings.NewReader(fmt.Sprintf(
+					`{"num": 1, "day": "1", "month": "1", "year": "2006", "title": "Barrel", "img": %q, "link": %q}`,
+					img,
+					link,
+				))),
+			}, nil
+		}
+		return getImageResponse(t, "png", nil, nil), nil
+	}
+}
+
+func TestWithAllowedHosts(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name          string
+		img           string
+		link          string
+		opts          []xkcd.ClientOption
+		expectedError string
+	}{
+		{name: "default hosts", img: "https://imgs.xkcd.com/comics/barrel.png", link: "https://xkcd.com/1/"},
+		{name: "subdomain", img: "https://imgs.xkcd.com/comics/barrel.png", link: "https://www.xkcd.com/1/"},
+		{name: "case and trailing dot", img: "https://IMGS.xkcd.com./comics/barrel.png"},
+		{
+			name:          "image on internal address",
+			img:           "http://169.254.169.254/latest/meta-data/",
+			expectedError: "post image URL is invalid: host is not allowed: 169.254.169.254",
+		},
+		{
+			name:          "image on other host",
+			img:           "https://evilxkcd.com/barrel.png",
+			expectedError: "post image URL is invalid: host is not allowed: evilxkcd.com",
+		},
+		{
+			name:          "link on other host",
+			img:           "https://imgs.xkcd.com/comics/barrel.png",
+			link:          "http://localhost:8080/",
+			expectedError: "post link URL is invalid: host is not allowed: localhost",
+		},
+		{
+			name: "allowed hosts",
+			img:  "https://images.example.com/barrel.png",
+			link: "https://example.com/1/",
+			opts: []xkcd.ClientOption{xkcd.WithAllowedHosts("example.com")},
+		},
+		{
+			name:          "allowed hosts replace default ones",
+			img:           "https://imgs.xkcd.com/comics/barrel.png",
+			opts:          []xkcd.ClientOption{xkcd.WithAllowedHosts("example.com")},
+			expectedError: "host is not allowed: imgs.xkcd.com",
+		},
+		{
+			name: "mirror hosts",
+			img:  "https://imgs.mirror.example.com/comics/barrel.png",
+			link: "https://api.mirror.example.com/1/",
+			opts: []xkcd.ClientOption{xkcd.WithMirrors(xkcd.Mirror{
+				BaseURL:      "https://api.mirror.example.com",
+				ImageBaseURL: "https://imgs.mirror.example.com",
+			})},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var requested []string
+			c := getClient(t, sendPostWithURLs(t, tc.img, tc.link, &requested), nil, tc.opts...)
+			p, err := c.GetPost(ctx, 1)
+			if tc.expectedError != "" {
+				assert.Nil(t, p, "expected no post")
+				assert.ErrorIs(t, err, xkcd.ErrHostNotAllowed, "expected error to be ErrHostNotAllowed")
+				assert.ErrorIs(t, err, xkcd.ErrAPIError, "expected error to be ErrAPIError")
+				assert.ErrorContains(t, err, tc.expectedError, "expected error to have correct message")
+				return
+			}
+			require.NoError(t, err, "expected no error while getting post")
+			rdr, err := p.GetImageContent(ctx)
+			require.NoError(t, err, "expected no error while getting image")
+			assert.NoError(t, rdr.Close(), "expected no error while closing image")
+		})
+	}
+
+	t.Run("image requests are checked", func(t *testing.T) {
+		var requested []string
+		c := getClient(t, sendPostWithURLs(t, "https://imgs.xkcd.com/comics/barrel.png", "", &requested), nil)
+		p, err := c.GetPost(ctx, 1)
+		require.NoError(t, err, "expected no error while getting post")
+		p.Img = "http://10.0.0.1/barrel.png"
+		_, err = p.GetImageContent(ctx)
+		assert.ErrorIs(t, err, xkcd.ErrHostNotAllowed, "expected error to be ErrHostNotAllowed")
+		_, err = p.GetImageInfo(ctx)
+		assert.ErrorIs(t, err, xkcd.ErrHostNotAllowed, "expected error to be ErrHostNotAllowed")
+		assert.Equal(t, []string{"xkcd.com"}, requested, "expected image not to be requested")
+	})
+
+	t.Run("posts not retrieved by a client are restricted to default hosts", func(t *testing.T) {
+		var requested []string
+		client := &http.Client{Transport: &mockRoundTripper{
+			mock: sendPostWithURLs(t, "", "", &requested),
+			t:    t,
+		}}
+		p := xkcd.NewPost(client, slog.Default())
+		p.Img = "https://images.example.com/barrel.png"
+		_, err := p.GetImageContent(ctx)
+		assert.ErrorIs(t, err, xkcd.ErrHostNotAllowed, "expected error to be ErrHostNotAllowed")
+		assert.Empty(t, requested, "expected image not to be requested")
+
+		p.Img = "https://imgs.xkcd.com/comics/barrel.png"
+		rdr, err := p.GetImageContent(ctx)
+		require.NoError(t, err, "expected no error while getting image")
+		assert.NoError(t, rdr.Close(), "expected no error while closing image")
+		assert.Equal(t, []string{"imgs.xkcd.com"}, requested, "expected image to be requested")
+	})
+}
+
+// getRedirectingHTTPClient returns a NewHTTPClient http client serving a post, and redirecting images to location.
+func getRedirectingHTTPClient(t *testing.T, location string, requested *[]string) *http.Client {
+	t.Helper()
+	client := xkcd.NewHTTPClient(nil)
+	client.Transport = &mockRoundTripper{
+		mock: func(r *http.Request) (*http.Response, error) {
+			*requested = append(*requested, r.URL.String())
+			switch r.URL.Host {
+			case "xkcd.com":
+				return sendValidPost(t)
+			case "imgs.xkcd.com":
+				return &http.Response{
+					StatusCode: http.StatusFound,
+					Header:     http.Header{"Location": []string{location}},
+					Body:       http.NoBody,
+				}, nil
+			}
+			return getImageResponse(t, "png", nil, nil), nil
+		},
+		t: t,
+	}
+	return client
+}
+
+func TestNewHTTPClient_Redirects(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("redirects to other hosts are denied", func(t *testing.T) {
+		var requested []string
+		c := getClient(t, nil, nil,
+			xkcd.WithClient(getRedirectingHTTPClient(t, "http://internal.example.com/latest/meta-data/", &requested)),
+			xkcd.WithRetryPolicy(xkcd.DefaultRetryPolicy()),
+		)
+		p, err := c.GetPost(ctx, 1)
+		require.NoError(t, err, "expected no error while getting post")
+		_, err = p.GetImageContent(ctx)
+		assert.ErrorIs(t, err, xkcd.ErrHostNotAllowed, "expected error to be ErrHostNotAllowed")
+		assert.ErrorContains(t, err, "host is not allowed: internal.example.com", "expected error to have correct message")
+		var apiErr *xkcd.APIError
+		require.ErrorAs(t, err, &apiErr, "expected an APIError")
+		assert.False(t, apiErr.Retryable(), "expected error not to be retryable")
+		assert.Equal(t, []string{
+			"https://xkcd.com/1/info.0.json",
+			"https://imgs.xkcd.com/comics/barrel_cropped_(1).jpg",
+		}, requested, "expected redirect not to be followed nor retried")
+	})
+
+	t.Run("redirects to allowed hosts are followed", func(t *testing.T) {
+		var requested []string
+		client := getRedirectingHTTPClient(t, "https://www.xkcd.com/comics/barrel.png", &requested)
+		resp, err := client.Get("https://imgs.xkcd.com/comics/barrel.png")
+		require.NoError(t, err, "expected redirect to be followed")
+		assert.NoError(t, resp.Body.Close(), "expected no error while closing body")
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "expected image to be served")
+		assert.Equal(t, []string{
+			"https://imgs.xkcd.com/comics/barrel.png",
+			"https://www.xkcd.com/comics/barrel.png",
+		}, requested, "expected redirect to be followed")
+	})
+
+	t.Run("allowed hosts", func(t *testing.T) {
+		var requested []string
+		client := getRedirectingHTTPClient(t, "https://images.example.com/barrel.png", &requested)
+		_, err := client.Get("https://imgs.xkcd.com/comics/barrel.png")
+		assert.ErrorIs(t, err, xkcd.ErrHostNotAllowed, "expected redirect to be denied by default")
+
+		client = xkcd.NewHTTPClient([]string{"xkcd.com", "example.com"})
+		client.Transport = getRedirectingHTTPClient(t, "https://images.example.com/barrel.png", &requested).Transport
+		resp, err := client.Get("https://imgs.xkcd.com/comics/barrel.png")
+		require.NoError(t, err, "expected redirect to an allowed host to be followed")
+		assert.NoError(t, resp.Body.Close(), "expected no error while closing body")
+	})
+}
+
+func TestWithAllowedNetworks(t *testing.T) {
+	ctx := context.Background()
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		resp, _ := sendValidPost(t)
+		_, _ = io.Copy(w, resp.Body)
+	}))
+	defer srv.Close()
+
+	t.Run("internal addresses are denied", func(t *testing.T) {
+		c := xkcd.New(xkcd.WithBaseURL(srv.URL), xkcd.WithRetryPolicy(xkcd.DefaultRetryPolicy()))
+		_, err := c.GetPost(ctx, 1)
+		assert.ErrorIs(t, err, xkcd.ErrAddressNotAllowed, "expected error to be ErrAddressNotAllowed")
+		assert.ErrorIs(t, err, xkcd.ErrAPIError, "expected error to be ErrAPIError")
+		var apiErr *xkcd.APIError
+		require.ErrorAs(t, err, &apiErr, "expected an APIError")
+		assert.False(t, apiErr.Retryable(), "expected error not to be retryable")
+		assert.Zero(t, requests.Load(), "expected server not to be requested")
+	})
+
+	t.Run("allowed networks", func(t *testing.T) {
+		c := xkcd.New(allowLoopback(), xkcd.WithBaseURL(srv.URL))
+		_, err := c.GetPost(ctx, 1)
+		assert.NoError(t, err, "expected no error")
+		assert.Equal(t, int32(1), requests.Load(), "expected server to be requested")
+	})
+
+	t.Run("given http clients are not restricted", func(t *testing.T) {
+		c := xkcd.New(xkcd.WithClient(&http.Client{}), xkcd.WithBaseURL(srv.URL))
+		_, err := c.GetPost(ctx, 1)
+		assert.NoError(t, err, "expected no error")
+	})
+
+	t.Run("http client", func(t *testing.T) {
+		resp, err := xkcd.NewHTTPClient(nil).Get(srv.URL)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		assert.ErrorIs(t, err, xkcd.ErrAddressNotAllowed, "expected error to be ErrAddressNotAllowed")
+	})
+
+	t.Run("proxies are not used", func(t *testing.T) {
+		transport, ok := xkcd.NewHTTPClient(nil).Transport.(*http.Transport)
+		require.True(t, ok, "expected a http transport")
+		assert.Nil(t, transport.Proxy, "expected no proxy, which would be the only address checked")
+	})
+}
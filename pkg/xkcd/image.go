@@ -1,13 +1,20 @@
 package xkcd
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"image"
 	"io"
 	"log/slog"
+	"mime"
 	"net/http"
+	"net/url"
+	"path"
 	"slices"
+	"strings"
 
 	// JPEG image format support.
 	_ "image/jpeg"
@@ -15,39 +22,276 @@ import (
 	_ "image/png"
 	// GIF image format support.
 	_ "image/gif"
+
+	// WebP image format support.
+	_ "golang.org/x/image/webp"
 )
 
 const (
 	contentTypeHeader = "Content-Type"
+	// sniffLength is the number of bytes read to detect the format of images, as http.DetectContentType.
+	sniffLength = 512
 )
 
+// imageFormats are the image formats accepted from their content type alone, by media type.
+var imageFormats = map[string]string{
+	"image/jpeg": "jpeg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+// strictImageFormats are the image formats accepted with a strict content type, by media type.
+var strictImageFormats = map[string]string{
+	"image/jpeg": "jpeg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// ImageContent is a reader to the content of a post image.
+type ImageContent struct {
+	// Format is the image format, as named by the image package: "jpeg", "png", "gif", "webp", or the name
+	// of any other format registered with image.RegisterFormat.
+	Format string
+	// ContentType is the media type of the image.
+	ContentType string
+
+	body   io.ReadCloser
+	reader io.Reader
+	url    string
+}
+
+// Read implements io.Reader.
+func (c *ImageContent) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// Close implements io.Closer.
+func (c *ImageContent) Close() error {
+	return c.body.Close()
+}
+
+// ImageVariant is a resolution variant of post images.
+type ImageVariant int
+
+const (
+	// Variant1x is the standard resolution image, at Post.Img.
+	Variant1x ImageVariant = iota
+	// Variant2x is the double resolution image, served for most posts since 2012 with a _2x suffix.
+	Variant2x
+)
+
+// String implements fmt.Stringer.
+func (v ImageVariant) String() string {
+	if v == Variant2x {
+		return "2x"
+	}
+	return "1x"
+}
+
+// ParseImageVariant parses an image variant name as returned by ImageVariant.String.
+func ParseImageVariant(s string) (ImageVariant, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1x", "":
+		return Variant1x, nil
+	case "2x":
+		return Variant2x, nil
+	}
+	return Variant1x, fmt.Errorf("unknown image variant %q", s)
+}
+
+// variantURL returns the URL of the image img variant.
+func variantURL(img string, variant ImageVariant) (string, error) {
+	if variant != Variant2x {
+		return img, nil
+	}
+	u, err := url.Parse(img)
+	if err != nil {
+		return "", fmt.Errorf("invalid image URL: %w", err)
+	}
+	ext := path.Ext(u.Path)
+	u.Path = strings.TrimSuffix(u.Path, ext) + "_2x" + ext
+	u.RawPath = ""
+	return u.String(), nil
+}
+
 // GetImageContent returns a reader to the content of the image associated with the post image.
-func (p *Post) GetImageContent(ctx context.Context, client ...HTTPClient) (io.ReadCloser, error) {
+// For posts retrieved by a Client, the image URL is rewritten with the client image base URL, and
+// its mirrors are tried in order on ErrAPIError.
+// Images served as image/jpeg, image/png or image/gif are accepted, others are accepted if their first
+// bytes are those of a format registered with the image package, unless the client has a strict content
+// type, see WithStrictContentType.
+func (p *Post) GetImageContent(ctx context.Context, client ...HTTPClient) (*ImageContent, error) {
 	if p.Img == "" {
 		return nil, fmt.Errorf("image URL is missing")
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Img, http.NoBody)
+	return p.getImageContent(ctx, p.Img, client...)
+}
+
+// GetImageContentVariant is GetImageContent for an image variant.
+// If the variant is not Variant1x and the image server does not have it, it falls back to Variant1x.
+func (p *Post) GetImageContentVariant(ctx context.Context, variant ImageVariant, client ...HTTPClient) (*ImageContent, error) {
+	return fromImageVariant(p, variant, func(img string) (*ImageContent, error) {
+		return p.getImageContent(ctx, img, client...)
+	})
+}
+
+// fromImageVariant calls get with the URL of the post image variant, and again with the URL of the Variant1x
+// image if the image server does not have the variant.
+func fromImageVariant[T any](p *Post, variant ImageVariant, get func(img string) (T, error)) (T, error) {
+	var zero T
+	if p.Img == "" {
+		return zero, fmt.Errorf("image URL is missing")
+	}
+	img, err := variantURL(p.Img, variant)
+	if err != nil {
+		return zero, err
+	}
+	v, err := get(img)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound && img != p.Img {
+		p.logger.Debug("image variant not found, falling back to 1x", slog.String("variant", variant.String()))
+		return get(p.Img)
+	}
+	return v, err
+}
+
+func (p *Post) getImageContent(ctx context.Context, img string, client ...HTTPClient) (*ImageContent, error) {
+	return fromImageURLs(ctx, p, img, func(imgURL string) (*ImageContent, error) {
+		return p.getImageContentFrom(ctx, imgURL, client...)
+	})
+}
+
+// fromImageURLs calls get with the URLs of the post image img in order, until it does not fail with ErrAPIError.
+func fromImageURLs[T any](ctx context.Context, p *Post, img string, get func(imgURL string) (T, error)) (T, error) {
+	var zero T
+	urls, err := p.imageURLs(img)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return zero, err
+	}
+	for k, imgURL := range urls {
+		var v T
+		v, err = get(imgURL)
+		if err == nil || !errors.Is(err, ErrAPIError) || ctx.Err() != nil {
+			return v, err
+		}
+		if k < len(urls)-1 {
+			p.logger.Warn("failed to get image, trying next mirror", slog.String("error", err.Error()), slog.String("url", urls[k+1]))
+		}
 	}
+	return zero, err
+}
+
+// imageClient returns the client to fetch the post image with.
+// Posts not retrieved by a client have no retry policy nor limits, and their images must be on
+// DefaultAllowedHosts.
+func (p *Post) imageClient() *Client {
+	if p.apiClient == nil {
+		return &Client{}
+	}
+	return p.apiClient
+}
+
+// imageURLs returns the URLs to fetch the post image img from, in order.
+func (p *Post) imageURLs(img string) ([]string, error) {
+	if p.apiClient == nil {
+		return []string{img}, nil
+	}
+	var urls []string
+	for _, mirror := range p.apiClient.mirrors() {
+		u, err := mirror.imageURL(img)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(urls, u) {
+			urls = append(urls, u)
+		}
+	}
+	return urls, nil
+}
+
+func (p *Post) getImageContentFrom(ctx context.Context, imgURL string, client ...HTTPClient) (*ImageContent, error) {
 	p.logger.Debug("fetching image")
-	resp, err := p.getClient(client...).Do(req)
+	apiClient := p.imageClient()
+	resp, err := apiClient.cachedDo(
+		ctx,
+		p.getClient(client...),
+		imgURL,
+		apiClient.maxImageBytes,
+		p.logger.With(slog.String("image_url", imgURL)),
+	)
 	if err != nil {
-		return nil, fmt.Errorf("%w: failed to send request: %w", ErrAPIError, err)
+		return nil, err
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%w: unexpected status code: %d", ErrAPIError, resp.StatusCode)
+		_ = resp.Body.Close()
+		return nil, newResponseError(imgURL, resp, fmt.Sprintf("unexpected status code: %d", resp.StatusCode), nil)
 	}
-	if !slices.Contains([]string{"image/jpeg", "image/png", "image/gif"}, resp.Header.Get(contentTypeHeader)) {
-		return nil, fmt.Errorf("%w: unexpected or undefined content-type: %v", ErrAPIError, resp.Header.Get(contentTypeHeader))
+	content, err := newImageContent(imgURL, resp, apiClient)
+	if err != nil {
+		_ = resp.Body.Close()
+		return nil, err
 	}
-	p.logger.Debug("got image response")
-	return resp.Body, nil
+	p.logger.Debug("got image response", slog.String("format", content.Format))
+	return content, nil
+}
+
+// newImageContent returns the image content of the response resp to a request to imgURL, limited to the maximum
+// image length of c, with its format from its content type, or detected from its first bytes if its content type
+// is not an image one and the content type of c is not strict.
+func newImageContent(imgURL string, resp *http.Response, c *Client) (*ImageContent, error) {
+	if c.maxImageBytes > 0 && resp.ContentLength > c.maxImageBytes {
+		return nil, &ImageTooLargeError{URL: imgURL, MaxBytes: c.maxImageBytes}
+	}
+	body := limitReader(imgURL, resp.Body, c.maxImageBytes)
+	header := resp.Header.Get(contentTypeHeader)
+	mediaType := header
+	formats := strictImageFormats
+	if !c.strictContentType {
+		// Parameters, such as a charset set by some servers, are ignored.
+		mediaType, _, _ = mime.ParseMediaType(header)
+		formats = imageFormats
+	}
+	if format, ok := formats[mediaType]; ok {
+		return &ImageContent{Format: format, ContentType: mediaType, body: resp.Body, reader: body, url: imgURL}, nil
+	}
+	if c.strictContentType {
+		return nil, newResponseError(imgURL, resp, fmt.Sprintf("unexpected or undefined content-type: %v", header), nil)
+	}
+
+	rdr := bufio.NewReaderSize(body, sniffLength)
+	head, err := rdr.Peek(sniffLength)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, newResponseError(imgURL, resp, "failed to read response", err)
+	}
+	format := sniffImageFormat(head)
+	if format == "" {
+		return nil, newResponseError(imgURL, resp, fmt.Sprintf("unexpected or undefined content-type: %v", header), nil)
+	}
+	contentType := http.DetectContentType(head)
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = "image/" + format
+	}
+	return &ImageContent{Format: format, ContentType: contentType, body: resp.Body, reader: rdr, url: imgURL}, nil
+}
+
+// sniffImageFormat returns the name of the format registered with the image package matching the
+// first bytes head of an image, or an empty string if none matches.
+func sniffImageFormat(head []byte) string {
+	// Decoding the configuration may fail on truncated data, but the format is known once matched.
+	_, format, _ := image.DecodeConfig(bytes.NewReader(head))
+	return format
 }
 
 // GetImage returns an image.Image of the post image.
 func (p *Post) GetImage(ctx context.Context, client ...HTTPClient) (image.Image, string, error) {
-	data, err := p.GetImageContent(ctx, client...)
+	return p.GetImageVariant(ctx, Variant1x, client...)
+}
+
+// GetImageVariant is GetImage for an image variant, falling back to Variant1x as GetImageContentVariant.
+// The size of the image is checked against the maximum number of pixels of the client before decoding it.
+func (p *Post) GetImageVariant(ctx context.Context, variant ImageVariant, client ...HTTPClient) (image.Image, string, error) {
+	data, err := p.GetImageContentVariant(ctx, variant, client...)
 	if err != nil {
 		return nil, "", err
 	}
@@ -56,6 +300,14 @@ func (p *Post) GetImage(ctx context.Context, client ...HTTPClient) (image.Image,
 			p.logger.Warn("failed to close response body", slog.String("error", err.Error()))
 		}
 	}(data)
+	var head bytes.Buffer
+	cfg, _, err := image.DecodeConfig(io.TeeReader(data, &head))
+	if err != nil {
+		return nil, "", err
+	}
+	if err = p.imageClient().checkImagePixels(data.url, cfg.Width, cfg.Height); err != nil {
+		return nil, "", err
+	}
 	p.logger.Debug("decoding image")
-	return image.Decode(data)
+	return image.Decode(io.MultiReader(&head, data))
 }
@@ -3,9 +3,12 @@ package xkcd_test
 import (
 	"context"
 	"errors"
+	"image"
+	"image/color"
 	"io"
 	"log/slog"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -175,6 +178,121 @@ func TestPost_GetImageContent(t *testing.T) {
 	})
 }
 
+func TestPost_GetImageContent_Format(t *testing.T) {
+	ctx := context.Background()
+	image.RegisterFormat("xkcdtest", "XKCDTEST", func(io.Reader) (image.Image, error) {
+		return image.NewGray(image.Rect(0, 0, 1, 1)), nil
+	}, func(io.Reader) (image.Config, error) {
+		return image.Config{ColorModel: color.GrayModel, Width: 1, Height: 1}, nil
+	})
+
+	getContent := func(t *testing.T, imgResp *http.Response, opts ...xkcd.ClientOption) (*xkcd.ImageContent, error) {
+		t.Helper()
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			if r.URL.Host == "imgs.xkcd.com" {
+				return imgResp, nil
+			}
+			return sendValidPost(t)
+		}, nil, opts...)
+		p, err := c.GetPost(ctx, 1)
+		require.NoError(t, err, "expected no error while getting post")
+		return p.GetImageContent(ctx)
+	}
+
+	for _, tc := range []struct {
+		name                string
+		imgType             string
+		contentType         string
+		expectedFormat      string
+		expectedContentType string
+		expectedLen         int
+	}{
+		{"content type", "jpg", "image/jpeg", "jpeg", "image/jpeg", 24848},
+		{"content type with parameters", "png", "image/png; charset=binary", "png", "image/png", 54845},
+		{"upper cased content type", "gif", "IMAGE/GIF", "gif", "image/gif", 56039},
+		{"sniffed", "png", "application/octet-stream", "png", "image/png", 54845},
+		{"sniffed without content type", "jpg", "", "jpeg", "image/jpeg", 24848},
+		{"sniffed with mislabeled content type", "gif", "text/plain", "gif", "image/gif", 56039},
+		{"webp content type", "webp", "image/webp", "webp", "image/webp", 2450},
+		{"sniffed webp", "webp", "application/octet-stream", "webp", "image/webp", 2450},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			imgResp := getImageResponse(t, tc.imgType, nil, nil)
+			defer imgResp.Body.Close()
+			imgResp.Header.Set("Content-Type", tc.contentType)
+			rdr, err := getContent(t, imgResp)
+			require.NoError(t, err, "expected no error while getting image")
+			assert.Equal(t, tc.expectedFormat, rdr.Format, "expected format to be detected")
+			assert.Equal(t, tc.expectedContentType, rdr.ContentType, "expected content type to be detected")
+			data, err := io.ReadAll(rdr)
+			assert.NoError(t, err, "expected read to not return an error")
+			assert.Len(t, data, tc.expectedLen, "expected sniffed bytes to be read")
+		})
+	}
+
+	t.Run("registered format", func(t *testing.T) {
+		imgResp := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"image/x-xkcdtest"}},
+			Body:       io.NopCloser(strings.NewReader("XKCDTEST data")),
+		}
+		rdr, err := getContent(t, imgResp)
+		require.NoError(t, err, "expected no error while getting image")
+		assert.Equal(t, "xkcdtest", rdr.Format, "expected registered format to be detected")
+		assert.Equal(t, "image/xkcdtest", rdr.ContentType, "expected content type from format")
+		data, err := io.ReadAll(rdr)
+		assert.NoError(t, err, "expected read to not return an error")
+		assert.Equal(t, "XKCDTEST data", string(data), "expected data to be read correctly")
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		imgResp := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/octet-stream"}},
+			Body:       io.NopCloser(strings.NewReader("not an image")),
+		}
+		rdr, err := getContent(t, imgResp)
+		require.Nil(t, rdr, "expected nil reader")
+		assert.ErrorIs(t, err, xkcd.ErrAPIError, "expected error to be ErrAPIError")
+		assert.ErrorContains(t, err, "unexpected or undefined content-type: application/octet-stream", "expected error to have correct message")
+	})
+
+	t.Run("error while sniffing", func(t *testing.T) {
+		imgResp := getImageResponse(t, "png", errors.New("yes, rico, kaboom"), nil)
+		defer imgResp.Body.Close()
+		imgResp.Header.Set("Content-Type", "application/octet-stream")
+		rdr, err := getContent(t, imgResp)
+		require.Nil(t, rdr, "expected nil reader")
+		assert.ErrorIs(t, err, xkcd.ErrAPIError, "expected error to be ErrAPIError")
+		assert.ErrorContains(t, err, "failed to read response: yes, rico, kaboom", "expected error to have correct message")
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		imgResp := getImageResponse(t, "png", nil, nil)
+		defer imgResp.Body.Close()
+		rdr, err := getContent(t, imgResp, xkcd.WithStrictContentType(true))
+		require.NoError(t, err, "expected no error while getting image")
+		assert.Equal(t, "png", rdr.Format, "expected format from content type")
+
+		for _, contentType := range []string{"image/png; charset=binary", "application/octet-stream"} {
+			imgResp := getImageResponse(t, "png", nil, nil)
+			defer imgResp.Body.Close()
+			imgResp.Header.Set("Content-Type", contentType)
+			rdr, err := getContent(t, imgResp, xkcd.WithStrictContentType(true))
+			require.Nil(t, rdr, "expected nil reader")
+			assert.ErrorIs(t, err, xkcd.ErrAPIError, "expected error to be ErrAPIError")
+			assert.ErrorContains(t, err, "unexpected or undefined content-type: "+contentType, "expected error to have correct message")
+		}
+
+		webpResp := getImageResponse(t, "webp", nil, nil)
+		defer webpResp.Body.Close()
+		rdr, err = getContent(t, webpResp, xkcd.WithStrictContentType(true))
+		require.Nil(t, rdr, "expected nil reader")
+		assert.ErrorIs(t, err, xkcd.ErrAPIError, "expected error to be ErrAPIError")
+		assert.ErrorContains(t, err, "unexpected or undefined content-type: image/webp", "expected WebP not to be accepted")
+	})
+}
+
 func TestPost_GetImage(t *testing.T) {
 	ctx := context.Background()
 
@@ -271,6 +389,26 @@ func TestPost_GetImage(t *testing.T) {
 		assert.True(t, closeCalled, "expected close to have been called on image response body")
 	})
 
+	t.Run("happy path webp", func(t *testing.T) {
+		imgResp := getImageResponse(t, "webp", nil, nil)
+		defer imgResp.Body.Close()
+		expectedPost, resp := getRandomPost(t, nil, nil)
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			if r.URL.String() == expectedPost.Img {
+				return imgResp, nil
+			}
+			return resp, nil
+		}, nil)
+		p, err := c.GetPost(ctx, 1)
+		require.NoError(t, err, "expected no error while getting post")
+		require.NotNil(t, p, "expected non-nil post")
+		img, imgType, err := p.GetImage(context.Background())
+		assert.NoError(t, err, "expected no error while getting image")
+		require.NotNil(t, img, "expected non-nil image")
+		assert.Equal(t, "webp", imgType, "expected image to be decoded correctly")
+		assert.Equal(t, 150, img.Bounds().Dx(), "expected image to be decoded correctly")
+	})
+
 	t.Run("error while getting image content", func(t *testing.T) {
 		imgResp := getImageResponse(t, "error", nil, nil)
 		defer imgResp.Body.Close()
@@ -312,3 +450,71 @@ func TestPost_GetImage(t *testing.T) {
 		assert.Empty(t, imgType, "expected empty image type")
 	})
 }
+
+func TestPost_GetImageContentVariant(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("2x variant", func(t *testing.T) {
+		var requested []string
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			if r.URL.Host == "imgs.xkcd.com" {
+				requested = append(requested, r.URL.Path)
+				return getImageResponse(t, "png", nil, nil), nil
+			}
+			return sendValidPost(t)
+		}, nil)
+		p, err := c.GetPost(ctx, 1)
+		require.NoError(t, err, "expected no error while getting post")
+		rdr, err := p.GetImageContentVariant(ctx, xkcd.Variant2x)
+		require.NoError(t, err, "expected no error while getting image")
+		defer rdr.Close()
+		assert.Equal(t, []string{"/comics/barrel_cropped_(1)_2x.jpg"}, requested, "expected 2x image to be requested")
+	})
+
+	t.Run("fallback to 1x", func(t *testing.T) {
+		var requested []string
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			if r.URL.Host == "imgs.xkcd.com" {
+				requested = append(requested, r.URL.Path)
+				if r.URL.Path == "/comics/barrel_cropped_(1)_2x.jpg" {
+					return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody}, nil
+				}
+				return getImageResponse(t, "jpg", nil, nil), nil
+			}
+			return sendValidPost(t)
+		}, nil)
+		p, err := c.GetPost(ctx, 1)
+		require.NoError(t, err, "expected no error while getting post")
+		img, format, err := p.GetImageVariant(ctx, xkcd.Variant2x)
+		require.NoError(t, err, "expected no error while getting image")
+		assert.NotNil(t, img, "expected an image")
+		assert.Equal(t, "jpeg", format, "expected 1x image")
+		assert.Equal(t, []string{"/comics/barrel_cropped_(1)_2x.jpg", "/comics/barrel_cropped_(1).jpg"}, requested, "expected 1x image to be requested after 2x")
+	})
+
+	t.Run("other errors do not fall back", func(t *testing.T) {
+		requests := 0
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			if r.URL.Host == "imgs.xkcd.com" {
+				requests++
+				return &http.Response{StatusCode: http.StatusForbidden, Body: http.NoBody}, nil
+			}
+			return sendValidPost(t)
+		}, nil)
+		p, err := c.GetPost(ctx, 1)
+		require.NoError(t, err, "expected no error while getting post")
+		_, err = p.GetImageContentVariant(ctx, xkcd.Variant2x)
+		assert.ErrorIs(t, err, xkcd.ErrAPIError, "expected API error")
+		assert.Equal(t, 1, requests, "expected 1x image not to be requested")
+	})
+}
+
+func TestParseImageVariant(t *testing.T) {
+	for _, variant := range []xkcd.ImageVariant{xkcd.Variant1x, xkcd.Variant2x} {
+		parsed, err := xkcd.ParseImageVariant(variant.String())
+		require.NoError(t, err, "expected no error")
+		assert.Equal(t, variant, parsed, "expected variant to be parsed")
+	}
+	_, err := xkcd.ParseImageVariant("3x")
+	assert.Error(t, err, "expected an error")
+}
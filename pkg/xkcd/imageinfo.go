@@ -0,0 +1,317 @@
+package xkcd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// imageInfoRangeLength is the length of the beginning of images requested to read their information,
+// which is enough for the headers of most images.
+const imageInfoRangeLength = 64 << 10
+
+// ImageInfo is the information of a post image, read without decoding it.
+type ImageInfo struct {
+	// Format is the image format, as named by the image package.
+	Format string `json:"format"`
+	// Width is the width of the image in pixels.
+	Width int `json:"width"`
+	// Height is the height of the image in pixels.
+	Height int `json:"height"`
+	// Frames is the number of frames of GIF images, zero for other formats.
+	Frames int `json:"frames,omitempty"`
+	// Size is the length of the image content in bytes, -1 if the image server did not tell it.
+	Size int64 `json:"size"`
+}
+
+// GetImageInfo returns the information of the post image, from its beginning only when the image server
+// supports range requests, except for GIF images which are read entirely to count their frames. The rest of
+// the image is requested from the end of its beginning if the beginning is not enough.
+// Images are accepted as GetImageContent.
+func (p *Post) GetImageInfo(ctx context.Context, client ...HTTPClient) (*ImageInfo, error) {
+	return p.GetImageInfoVariant(ctx, Variant1x, client...)
+}
+
+// GetImageInfoVariant is GetImageInfo for an image variant, falling back to Variant1x as GetImageContentVariant.
+func (p *Post) GetImageInfoVariant(ctx context.Context, variant ImageVariant, client ...HTTPClient) (*ImageInfo, error) {
+	return p.getImageInfoVariant(ctx, variant, true, client...)
+}
+
+// GetImageDimensions returns the width and height of the post image. It is cheaper than GetImageInfo, as GIF
+// images are not read entirely and the length of the image is not looked up.
+func (p *Post) GetImageDimensions(ctx context.Context, client ...HTTPClient) (int, int, error) {
+	return p.GetImageDimensionsVariant(ctx, Variant1x, client...)
+}
+
+// GetImageDimensionsVariant is GetImageDimensions for an image variant, falling back to Variant1x as
+// GetImageContentVariant.
+func (p *Post) GetImageDimensionsVariant(ctx context.Context, variant ImageVariant, client ...HTTPClient) (int, int, error) {
+	info, err := p.getImageInfoVariant(ctx, variant, false, client...)
+	if err != nil {
+		return 0, 0, err
+	}
+	return info.Width, info.Height, nil
+}
+
+// getImageInfoVariant returns the information of the post image variant, with its GIF frames and its length
+// only if full is true.
+func (p *Post) getImageInfoVariant(ctx context.Context, variant ImageVariant, full bool, client ...HTTPClient) (*ImageInfo, error) {
+	return fromImageVariant(p, variant, func(img string) (*ImageInfo, error) {
+		return fromImageURLs(ctx, p, img, func(imgURL string) (*ImageInfo, error) {
+			return p.getImageInfoFrom(ctx, imgURL, full, client...)
+		})
+	})
+}
+
+func (p *Post) getImageInfoFrom(ctx context.Context, imgURL string, full bool, client ...HTTPClient) (*ImageInfo, error) {
+	logger := p.logger.With(slog.String("image_url", imgURL))
+	logger.Debug("fetching image information")
+	apiClient := p.imageClient()
+	header := http.Header{}
+	header.Set("Range", fmt.Sprintf("bytes=0-%d", imageInfoRangeLength-1))
+	resp, err := apiClient.do(ctx, p.getClient(client...), http.MethodGet, imgURL, header, logger)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		_ = resp.Body.Close()
+		return nil, newResponseError(imgURL, resp, fmt.Sprintf("unexpected status code: %d", resp.StatusCode), nil)
+	}
+	content, err := newImageContent(imgURL, resp, apiClient)
+	if err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+	defer content.Close()
+
+	size := contentSize(resp)
+	var r io.Reader = content
+	var head bytes.Buffer
+	complete := resp.StatusCode == http.StatusOK || (size >= 0 && size <= imageInfoRangeLength)
+	if !complete {
+		// The beginning of the image is kept, so that only the rest of it is fetched if it is not enough.
+		r = io.TeeReader(content, &head)
+	}
+	info, complete, err := readImageInfo(r, complete, full)
+	if err != nil {
+		return nil, err
+	}
+	if !complete {
+		if _, err = io.Copy(io.Discard, r); err != nil {
+			return nil, fmt.Errorf("failed to read image: %w", err)
+		}
+		logger.Debug("image beginning is not enough, fetching the rest of the image")
+		rest, err := p.getImageRestFrom(ctx, imgURL, head.Len(), logger, client...)
+		if err != nil {
+			return nil, err
+		}
+		defer rest.Body.Close()
+		var data io.Reader = rest.Body
+		if rest.StatusCode == http.StatusPartialContent {
+			data = io.MultiReader(&head, rest.Body)
+		}
+		info, _, err = readImageInfo(limitReader(imgURL, data, apiClient.maxImageBytes), true, full)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !full {
+		return info, nil
+	}
+	if info.Size < 0 {
+		info.Size = size
+	}
+	if info.Size < 0 {
+		info.Size = apiClient.headContentSize(ctx, p.getClient(client...), imgURL, logger)
+	}
+	return info, nil
+}
+
+// getImageRestFrom requests the image at imgURL from offset. The response is either the rest of the image, or
+// the whole image if the image server does not honor the range.
+func (p *Post) getImageRestFrom(
+	ctx context.Context,
+	imgURL string,
+	offset int,
+	logger *slog.Logger,
+	client ...HTTPClient,
+) (*http.Response, error) {
+	header := http.Header{}
+	header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	resp, err := p.imageClient().do(ctx, p.getClient(client...), http.MethodGet, imgURL, header, logger)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK ||
+		(resp.StatusCode == http.StatusPartialContent &&
+			strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset))) {
+		return resp, nil
+	}
+	_ = resp.Body.Close()
+	return nil, newResponseError(imgURL, resp, fmt.Sprintf("unexpected status code: %d", resp.StatusCode), nil)
+}
+
+// readImageInfo reads the information of the image content r, which is the whole content if complete is true,
+// counting the frames of GIF images if frames is true.
+// It returns false if r is not complete and the whole content is needed.
+func readImageInfo(r io.Reader, complete, frames bool) (*ImageInfo, bool, error) {
+	var head bytes.Buffer
+	cfg, format, err := image.DecodeConfig(io.TeeReader(r, &head))
+	if err != nil {
+		if !complete && (errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)) {
+			return nil, false, nil
+		}
+		return nil, true, fmt.Errorf("failed to decode image configuration: %w", err)
+	}
+	info := &ImageInfo{Format: format, Width: cfg.Width, Height: cfg.Height, Size: -1}
+	if format != "gif" || !frames {
+		return info, true, nil
+	}
+	if !complete {
+		return nil, false, nil
+	}
+	counter := &countingReader{r: io.MultiReader(&head, r)}
+	if info.Frames, err = countGIFFrames(counter); err != nil {
+		return nil, true, fmt.Errorf("failed to count GIF frames: %w", err)
+	}
+	if _, err = io.Copy(io.Discard, counter); err != nil {
+		return nil, true, fmt.Errorf("failed to read image: %w", err)
+	}
+	info.Size = counter.n
+	return info, true, nil
+}
+
+// contentSize returns the length of the whole content of the response resp, -1 if it is unknown.
+func contentSize(resp *http.Response) int64 {
+	if resp.StatusCode != http.StatusPartialContent {
+		// Images cannot be empty, a zero length is a response built without it.
+		if resp.ContentLength <= 0 {
+			return -1
+		}
+		return resp.ContentLength
+	}
+	// Content-Range: bytes <first>-<last>/<length>, with a * length if unknown.
+	_, length, ok := strings.Cut(resp.Header.Get("Content-Range"), "/")
+	if !ok {
+		return -1
+	}
+	size, err := strconv.ParseInt(length, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return size
+}
+
+// headContentSize returns the length of the content at rawURL from a HEAD request, -1 if it is unknown.
+func (c *Client) headContentSize(ctx context.Context, client HTTPClient, rawURL string, logger *slog.Logger) int64 {
+	resp, err := c.do(ctx, client, http.MethodHead, rawURL, nil, logger)
+	if err != nil {
+		logger.Debug("failed to get image size", slog.String("error", err.Error()))
+		return -1
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		logger.Debug("failed to get image size", slog.Int("status", resp.StatusCode))
+		return -1
+	}
+	return contentSize(resp)
+}
+
+// countGIFFrames returns the number of frames of the GIF image r, by walking its blocks without decoding them.
+func countGIFFrames(r io.Reader) (int, error) {
+	br := bufio.NewReader(r)
+	// Header and logical screen descriptor.
+	screen := make([]byte, 13)
+	if _, err := io.ReadFull(br, screen); err != nil {
+		return 0, err
+	}
+	if err := skipColorTable(br, screen[10]); err != nil {
+		return 0, err
+	}
+	frames := 0
+	for {
+		introducer, err := br.ReadByte()
+		if err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		switch introducer {
+		case 0x21: // Extension.
+			if _, err = br.ReadByte(); err != nil {
+				return 0, unexpectedEOF(err)
+			}
+		case 0x2c: // Image descriptor.
+			frames++
+			descriptor := make([]byte, 9)
+			if _, err = io.ReadFull(br, descriptor); err != nil {
+				return 0, unexpectedEOF(err)
+			}
+			if err = skipColorTable(br, descriptor[8]); err != nil {
+				return 0, err
+			}
+			// LZW minimum code size.
+			if _, err = br.ReadByte(); err != nil {
+				return 0, unexpectedEOF(err)
+			}
+		case 0x3b: // Trailer.
+			return frames, nil
+		default:
+			return 0, fmt.Errorf("invalid block introducer 0x%02x", introducer)
+		}
+		if err = skipSubBlocks(br); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// skipColorTable skips the color table following a GIF descriptor with the given packed fields, if it has one.
+func skipColorTable(br *bufio.Reader, fields byte) error {
+	if fields&0x80 == 0 {
+		return nil
+	}
+	_, err := br.Discard(3 << ((fields & 0x07) + 1))
+	return unexpectedEOF(err)
+}
+
+// skipSubBlocks skips GIF data sub-blocks, up to their terminator.
+func skipSubBlocks(br *bufio.Reader) error {
+	for {
+		size, err := br.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if size == 0 {
+			return nil
+		}
+		if _, err = br.Discard(int(size)); err != nil {
+			return unexpectedEOF(err)
+		}
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// countingReader is a reader counting the bytes read.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read implements io.Reader.
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
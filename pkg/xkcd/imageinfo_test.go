@@ -0,0 +1,298 @@
+package xkcd_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+// imageServer serves data at imgs.xkcd.com, honoring range requests if ranges is true, and with its length if
+// length is true, and records the requests it received.
+type imageServer struct {
+	contentType string
+	data        []byte
+	length      bool
+	ranges      bool
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func (s *imageServer) serve(t *testing.T) mockClient {
+	t.Helper()
+	return func(r *http.Request) (*http.Response, error) {
+		if r.URL.Host != "imgs.xkcd.com" {
+			return sendValidPost(t)
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+r.Header.Get("Range")))
+		s.mu.Unlock()
+		resp := &http.Response{
+			StatusCode:    http.StatusOK,
+			Header:        http.Header{"Content-Type": []string{s.contentType}},
+			Body:          io.NopCloser(bytes.NewReader(s.data)),
+			ContentLength: -1,
+		}
+		if s.length {
+			resp.ContentLength = int64(len(s.data))
+		}
+		// Open ranges, like bytes=<first>-, fail to scan only their last byte.
+		first, last := -1, len(s.data)-1
+		_, _ = fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &first, &last)
+		if s.ranges && first >= 0 {
+			last = min(last, len(s.data)-1)
+			resp.StatusCode = http.StatusPartialContent
+			resp.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", first, last, len(s.data)))
+			resp.Body = io.NopCloser(bytes.NewReader(s.data[first : last+1]))
+			resp.ContentLength = int64(last + 1 - first)
+		}
+		if r.Method == http.MethodHead {
+			resp.Body = http.NoBody
+		}
+		return resp, nil
+	}
+}
+
+func getImageInfo(t *testing.T, s *imageServer, variant xkcd.ImageVariant) (*xkcd.ImageInfo, error) {
+	t.Helper()
+	c := getClient(t, s.serve(t), nil)
+	p, err := c.GetPost(context.Background(), 1)
+	require.NoError(t, err, "expected no error while getting post")
+	return p.GetImageInfoVariant(context.Background(), variant)
+}
+
+// getNoisyGIF returns a GIF image of frames noisy frames, which are hardly compressed.
+func getNoisyGIF(t *testing.T, frames int) []byte {
+	t.Helper()
+	rnd := rand.New(rand.NewPCG(1, 2))
+	palette := color.Palette{color.Black, color.White}
+	anim := &gif.GIF{}
+	for range frames {
+		frame := image.NewPaletted(image.Rect(0, 0, 256, 256), palette)
+		for k := range frame.Pix {
+			frame.Pix[k] = uint8(rnd.IntN(2))
+		}
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var data bytes.Buffer
+	require.NoError(t, gif.EncodeAll(&data, anim))
+	return data.Bytes()
+}
+
+func TestPost_GetImageInfo(t *testing.T) {
+	jpg, err := os.ReadFile("testdata/image.jpg")
+	require.NoError(t, err)
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(jpg))
+	require.NoError(t, err)
+	expected := &xkcd.ImageInfo{Format: "jpeg", Width: cfg.Width, Height: cfg.Height, Size: int64(len(jpg))}
+
+	t.Run("range request", func(t *testing.T) {
+		s := &imageServer{contentType: "image/jpeg", data: jpg, ranges: true}
+		info, err := getImageInfo(t, s, xkcd.Variant1x)
+		require.NoError(t, err, "expected no error while getting image information")
+		assert.Equal(t, expected, info, "expected image information")
+		assert.Equal(t, []string{"GET /comics/barrel_cropped_(1).jpg bytes=0-65535"}, s.requests, "expected a single range request")
+	})
+
+	t.Run("range not supported", func(t *testing.T) {
+		s := &imageServer{contentType: "image/jpeg", data: jpg, length: true}
+		info, err := getImageInfo(t, s, xkcd.Variant1x)
+		require.NoError(t, err, "expected no error while getting image information")
+		assert.Equal(t, expected, info, "expected image information")
+		assert.Len(t, s.requests, 1, "expected a single request")
+	})
+
+	t.Run("size from head request", func(t *testing.T) {
+		s := &imageServer{contentType: "image/jpeg", data: jpg}
+		requests := 0
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			resp, err := s.serve(t)(r)
+			if r.Method == http.MethodHead {
+				requests++
+				resp.ContentLength = int64(len(jpg))
+			}
+			return resp, err
+		}, nil)
+		p, err := c.GetPost(context.Background(), 1)
+		require.NoError(t, err, "expected no error while getting post")
+		info, err := p.GetImageInfo(context.Background())
+		require.NoError(t, err, "expected no error while getting image information")
+		assert.Equal(t, expected, info, "expected image information")
+		assert.Equal(t, 1, requests, "expected a HEAD request")
+	})
+
+	t.Run("unknown size", func(t *testing.T) {
+		s := &imageServer{contentType: "image/jpeg", data: jpg}
+		info, err := getImageInfo(t, s, xkcd.Variant1x)
+		require.NoError(t, err, "expected no error while getting image information")
+		assert.Equal(t, int64(-1), info.Size, "expected unknown size")
+	})
+
+	t.Run("header larger than range", func(t *testing.T) {
+		// Comments before the frame header of a JPEG image push it beyond the requested range.
+		var data bytes.Buffer
+		data.Write(jpg[:2])
+		for range 2 {
+			data.Write([]byte{0xff, 0xfe, 0xea, 0x62})
+			data.Write(bytes.Repeat([]byte{'x'}, 0xea60))
+		}
+		data.Write(jpg[2:])
+		s := &imageServer{contentType: "image/jpeg", data: data.Bytes(), ranges: true}
+		info, err := getImageInfo(t, s, xkcd.Variant1x)
+		require.NoError(t, err, "expected no error while getting image information")
+		assert.Equal(t, cfg.Width, info.Width, "expected width")
+		assert.Equal(t, cfg.Height, info.Height, "expected height")
+		assert.Equal(t, int64(data.Len()), info.Size, "expected size")
+		assert.Equal(t, []string{
+			"GET /comics/barrel_cropped_(1).jpg bytes=0-65535",
+			"GET /comics/barrel_cropped_(1).jpg bytes=65536-",
+		}, s.requests, "expected rest of image to be fetched")
+	})
+
+	t.Run("header larger than range without range for the rest", func(t *testing.T) {
+		var data bytes.Buffer
+		data.Write(jpg[:2])
+		for range 2 {
+			data.Write([]byte{0xff, 0xfe, 0xea, 0x62})
+			data.Write(bytes.Repeat([]byte{'x'}, 0xea60))
+		}
+		data.Write(jpg[2:])
+		s := &imageServer{contentType: "image/jpeg", data: data.Bytes(), ranges: true}
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			if strings.HasSuffix(r.Header.Get("Range"), "-") {
+				r.Header.Del("Range")
+			}
+			return s.serve(t)(r)
+		}, nil)
+		p, err := c.GetPost(context.Background(), 1)
+		require.NoError(t, err, "expected no error while getting post")
+		info, err := p.GetImageInfo(context.Background())
+		require.NoError(t, err, "expected no error while getting image information")
+		assert.Equal(t, cfg.Width, info.Width, "expected width")
+		assert.Equal(t, cfg.Height, info.Height, "expected height")
+		assert.Equal(t, int64(data.Len()), info.Size, "expected size")
+	})
+
+	t.Run("gif frames", func(t *testing.T) {
+		palette := color.Palette{color.Black, color.White}
+		anim := &gif.GIF{}
+		for k := range 3 {
+			frame := image.NewPaletted(image.Rect(0, 0, 20, 10), palette)
+			frame.SetColorIndex(k, k, 1)
+			anim.Image = append(anim.Image, frame)
+			anim.Delay = append(anim.Delay, 10)
+		}
+		var data bytes.Buffer
+		require.NoError(t, gif.EncodeAll(&data, anim))
+		s := &imageServer{contentType: "image/gif", data: data.Bytes(), ranges: true}
+		info, err := getImageInfo(t, s, xkcd.Variant1x)
+		require.NoError(t, err, "expected no error while getting image information")
+		assert.Equal(t, &xkcd.ImageInfo{Format: "gif", Width: 20, Height: 10, Frames: 3, Size: int64(data.Len())}, info, "expected image information")
+		assert.Equal(t, []string{"GET /comics/barrel_cropped_(1).jpg bytes=0-65535"}, s.requests, "expected a single range request")
+	})
+
+	t.Run("gif frames larger than range", func(t *testing.T) {
+		data := getNoisyGIF(t, 8)
+		require.Greater(t, len(data), 65536, "expected GIF to be larger than range")
+		s := &imageServer{contentType: "image/gif", data: data, ranges: true}
+		info, err := getImageInfo(t, s, xkcd.Variant1x)
+		require.NoError(t, err, "expected no error while getting image information")
+		assert.Equal(t, &xkcd.ImageInfo{Format: "gif", Width: 256, Height: 256, Frames: 8, Size: int64(len(data))}, info, "expected image information")
+		assert.Equal(t, []string{
+			"GET /comics/barrel_cropped_(1).jpg bytes=0-65535",
+			"GET /comics/barrel_cropped_(1).jpg bytes=65536-",
+		}, s.requests, "expected rest of image to be fetched")
+	})
+
+	t.Run("variant fallback", func(t *testing.T) {
+		s := &imageServer{contentType: "image/jpeg", data: jpg, ranges: true}
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			if strings.HasSuffix(r.URL.Path, "_2x.jpg") {
+				return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody}, nil
+			}
+			return s.serve(t)(r)
+		}, nil)
+		p, err := c.GetPost(context.Background(), 1)
+		require.NoError(t, err, "expected no error while getting post")
+		info, err := p.GetImageInfoVariant(context.Background(), xkcd.Variant2x)
+		require.NoError(t, err, "expected no error while getting image information")
+		assert.Equal(t, expected, info, "expected 1x image information")
+	})
+
+	t.Run("not an image", func(t *testing.T) {
+		s := &imageServer{contentType: "image/png", data: []byte("not an image"), ranges: true}
+		info, err := getImageInfo(t, s, xkcd.Variant1x)
+		assert.Nil(t, info, "expected no image information")
+		assert.ErrorContains(t, err, "failed to decode image configuration", "expected error to have correct message")
+	})
+
+	t.Run("invalid status code", func(t *testing.T) {
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			if r.URL.Host == "imgs.xkcd.com" {
+				return &http.Response{StatusCode: http.StatusForbidden, Body: http.NoBody}, nil
+			}
+			return sendValidPost(t)
+		}, nil)
+		p, err := c.GetPost(context.Background(), 1)
+		require.NoError(t, err, "expected no error while getting post")
+		_, err = p.GetImageInfo(context.Background())
+		assert.ErrorIs(t, err, xkcd.ErrAPIError, "expected error to be ErrAPIError")
+		assert.ErrorContains(t, err, "unexpected status code: "+strconv.Itoa(http.StatusForbidden), "expected error to have correct message")
+	})
+}
+
+func TestPost_GetImageDimensions(t *testing.T) {
+	jpg, err := os.ReadFile("testdata/image.jpg")
+	require.NoError(t, err)
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(jpg))
+	require.NoError(t, err)
+
+	getDimensions := func(t *testing.T, s *imageServer) (int, int, error) {
+		t.Helper()
+		c := getClient(t, s.serve(t), nil)
+		p, err := c.GetPost(context.Background(), 1)
+		require.NoError(t, err, "expected no error while getting post")
+		return p.GetImageDimensions(context.Background())
+	}
+
+	t.Run("without size lookup", func(t *testing.T) {
+		s := &imageServer{contentType: "image/jpeg", data: jpg}
+		width, height, err := getDimensions(t, s)
+		require.NoError(t, err, "expected no error while getting image dimensions")
+		assert.Equal(t, cfg.Width, width, "expected width")
+		assert.Equal(t, cfg.Height, height, "expected height")
+		assert.Equal(t, []string{"GET /comics/barrel_cropped_(1).jpg bytes=0-65535"}, s.requests, "expected no HEAD request")
+	})
+
+	t.Run("gif without frames count", func(t *testing.T) {
+		s := &imageServer{contentType: "image/gif", data: getNoisyGIF(t, 8), ranges: true}
+		width, height, err := getDimensions(t, s)
+		require.NoError(t, err, "expected no error while getting image dimensions")
+		assert.Equal(t, 256, width, "expected width")
+		assert.Equal(t, 256, height, "expected height")
+		assert.Equal(t, []string{"GET /comics/barrel_cropped_(1).jpg bytes=0-65535"}, s.requests, "expected a single range request")
+	})
+
+	t.Run("not an image", func(t *testing.T) {
+		s := &imageServer{contentType: "image/png", data: []byte("not an image"), ranges: true}
+		_, _, err := getDimensions(t, s)
+		assert.ErrorContains(t, err, "failed to decode image configuration", "expected error to have correct message")
+	})
+}
@@ -0,0 +1,111 @@
+package xkcd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// postJSON is the JSON representation of a post, a superset of xkcd API info.0.json responses
+// with the publication date as an ISO 8601 date.
+type postJSON struct {
+	Num        uint   `json:"num"`
+	Title      string `json:"title"`
+	SafeTitle  string `json:"safe_title"`
+	Date       string `json:"date,omitempty"`
+	Day        string `json:"day"`
+	Month      string `json:"month"`
+	Year       string `json:"year"`
+	Link       string `json:"link"`
+	Img        string `json:"img"`
+	Alt        string `json:"alt"`
+	Transcript string `json:"transcript"`
+	News       string `json:"news"`
+}
+
+// MarshalJSON implements json.Marshaler.
+// Posts are encoded with the fields of xkcd API responses, and their publication date as a YYYY-MM-DD date.
+func (p Post) MarshalJSON() ([]byte, error) {
+	v := postJSON{
+		Num:        p.Num,
+		Title:      p.Title,
+		SafeTitle:  p.SafeTitle,
+		Day:        p.Day,
+		Month:      p.Month,
+		Year:       p.Year,
+		Link:       p.Link,
+		Img:        p.Img,
+		Alt:        p.Alt,
+		Transcript: p.Transcript,
+		News:       p.News,
+	}
+	if !p.Date.IsZero() {
+		v.Date = p.Date.Format(time.DateOnly)
+		// Posts which were not decoded from the API may only have a date.
+		if v.Day == "" && v.Month == "" && v.Year == "" {
+			v.Day = strconv.Itoa(p.Date.Day())
+			v.Month = strconv.Itoa(int(p.Date.Month()))
+			v.Year = strconv.Itoa(p.Date.Year())
+		}
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// It decodes both xkcd API responses, which only have the publication day, month and year, and posts
+// encoded by MarshalJSON. Date is set from the date if present, else from the day, month and year if they
+// are valid, and the day, month and year are set from the date if they are missing.
+func (p *Post) UnmarshalJSON(data []byte) error {
+	var v postJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	p.Num = v.Num
+	p.Title = v.Title
+	p.SafeTitle = v.SafeTitle
+	p.Day = v.Day
+	p.Month = v.Month
+	p.Year = v.Year
+	p.Link = v.Link
+	p.Img = v.Img
+	p.Alt = v.Alt
+	p.Transcript = v.Transcript
+	p.News = v.News
+	p.Date = time.Time{}
+
+	if v.Date == "" {
+		year, errYear := strconv.Atoi(v.Year)
+		month, errMonth := strconv.Atoi(v.Month)
+		day, errDay := strconv.Atoi(v.Day)
+		if errYear == nil && errMonth == nil && errDay == nil {
+			p.Date = time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local)
+		}
+		return nil
+	}
+	date, err := parseJSONDate(v.Date)
+	if err != nil {
+		return err
+	}
+	p.Date = date
+	if p.Day == "" && p.Month == "" && p.Year == "" {
+		p.Day = strconv.Itoa(date.Day())
+		p.Month = strconv.Itoa(int(date.Month()))
+		p.Year = strconv.Itoa(date.Year())
+	}
+	return nil
+}
+
+// parseJSONDate parses a YYYY-MM-DD date, or a RFC 3339 timestamp as encoded by previous versions, as a
+// publication date.
+func parseJSONDate(s string) (time.Time, error) {
+	date, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+	if err == nil {
+		return date, nil
+	}
+	ts, errTS := time.Parse(time.RFC3339, s)
+	if errTS != nil {
+		return time.Time{}, fmt.Errorf("invalid post date %q: %w", s, err)
+	}
+	return time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, time.Local), nil
+}
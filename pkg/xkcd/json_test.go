@@ -0,0 +1,92 @@
+package xkcd_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+func TestPost_MarshalJSON(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		p, err := getClient(t, nil, nil).GetPost(context.Background(), 1)
+		require.NoError(t, err, "expected no error")
+		b, err := json.Marshal(p)
+		require.NoError(t, err, "expected no error")
+		var decoded xkcd.Post
+		require.NoError(t, json.Unmarshal(b, &decoded), "expected no error")
+		checkPostAsExpected(t, p, &decoded)
+		assert.Equal(t, p.Link, decoded.Link, "expected link to be kept")
+	})
+
+	t.Run("fields", func(t *testing.T) {
+		b, err := json.Marshal(&xkcd.Post{
+			Num:   12,
+			Title: "Poisson",
+			Date:  time.Date(2006, time.January, 6, 0, 0, 0, 0, time.Local),
+		})
+		require.NoError(t, err, "expected no error")
+		var fields map[string]any
+		require.NoError(t, json.Unmarshal(b, &fields), "expected no error")
+		assert.Equal(t, "2006-01-06", fields["date"], "expected ISO 8601 date")
+		assert.Equal(t, "6", fields["day"], "expected day to be set from date")
+		assert.Equal(t, "1", fields["month"], "expected month to be set from date")
+		assert.Equal(t, "2006", fields["year"], "expected year to be set from date")
+		assert.EqualValues(t, 12, fields["num"], "expected lower case field names")
+		assert.NotContains(t, fields, "Date", "expected no Go formatted date")
+	})
+}
+
+func TestPost_UnmarshalJSON(t *testing.T) {
+	expectedDate := time.Date(2006, time.January, 1, 0, 0, 0, 0, time.Local)
+	cases := []struct {
+		name string
+		json string
+		date time.Time
+		day  string
+	}{
+		{
+			name: "api response",
+			json: `{"num": 1, "day": "1", "month": "1", "year": "2006", "title": "Barrel - Part 1"}`,
+			date: expectedDate,
+			day:  "1",
+		},
+		{
+			name: "date only",
+			json: `{"num": 1, "date": "2006-01-01", "title": "Barrel - Part 1"}`,
+			date: expectedDate,
+			day:  "1",
+		},
+		{
+			name: "previous output",
+			json: `{"num": 1, "Date": "2006-01-01T00:00:00Z", "day": "1", "month": "1", "year": "2006", "title": "Barrel - Part 1"}`,
+			date: expectedDate,
+			day:  "1",
+		},
+		{
+			name: "invalid day",
+			json: `{"num": 1, "day": "first", "month": "1", "year": "2006", "title": "Barrel - Part 1"}`,
+			day:  "first",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var p xkcd.Post
+			require.NoError(t, json.Unmarshal([]byte(c.json), &p), "expected no error")
+			assert.Equal(t, uint(1), p.Num, "expected number to be decoded")
+			assert.Equal(t, "Barrel - Part 1", p.Title, "expected title to be decoded")
+			assert.Equal(t, c.date, p.Date, "expected date to be decoded")
+			assert.Equal(t, c.day, p.Day, "expected day to be decoded")
+		})
+	}
+
+	t.Run("invalid date", func(t *testing.T) {
+		var p xkcd.Post
+		assert.ErrorContains(t, json.Unmarshal([]byte(`{"num": 1, "date": "yesterday"}`), &p), "invalid post date", "expected an error")
+	})
+}
@@ -0,0 +1,133 @@
+package xkcd
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateLimiter limits the rate of requests, allowing bursts of requests.
+type rateLimiter struct {
+	burst    time.Duration
+	interval time.Duration
+	mu       sync.Mutex
+	next     time.Time
+}
+
+func newRateLimiter(rps float64, burst int) *rateLimiter {
+	interval := time.Duration(float64(time.Second) / rps)
+	return &rateLimiter{
+		burst:    interval * time.Duration(max(burst, 1)-1),
+		interval: interval,
+	}
+}
+
+// wait blocks until a request is allowed, or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Add(-l.burst).Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// releasingBody is a response body which releases a concurrency slot once closed.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+// Close implements io.Closer.
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// send sends a request with client, once allowed by the rate limit and the concurrency cap of c.
+// The concurrency slot is held until the response body is closed.
+func (c *Client) send(client HTTPClient, req *http.Request) (*http.Response, error) {
+	if c.limiter != nil {
+		if err := c.limiter.wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+	if c.slots == nil {
+		return client.Do(req)
+	}
+	select {
+	case c.slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	release := func() { <-c.slots }
+	resp, err := client.Do(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// limitedReader is a reader failing with an *ImageTooLargeError once more than max bytes are read from it.
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	err       *ImageTooLargeError
+}
+
+// limitReader returns the content r of the image at imgURL limited to maxBytes, if it is positive.
+func limitReader(imgURL string, r io.Reader, maxBytes int64) io.Reader {
+	if maxBytes <= 0 {
+		return r
+	}
+	return &limitedReader{
+		r:         r,
+		remaining: maxBytes,
+		err:       &ImageTooLargeError{URL: imgURL, MaxBytes: maxBytes},
+	}
+}
+
+// Read implements io.Reader.
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, l.err
+	}
+	// Reading one more byte than remaining tells whether the limit is exceeded.
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n + int(l.remaining), l.err
+	}
+	return n, err
+}
+
+// checkImagePixels returns an *ImageTooLargeError if an image of the given size exceeds the maximum number
+// of pixels of c, if it has one.
+func (c *Client) checkImagePixels(imgURL string, width, height int) error {
+	if c.maxImagePixels > 0 && int64(width)*int64(height) > c.maxImagePixels {
+		return &ImageTooLargeError{URL: imgURL, MaxPixels: c.maxImagePixels, Width: width, Height: height}
+	}
+	return nil
+}
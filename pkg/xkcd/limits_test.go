@@ -0,0 +1,195 @@
+package xkcd_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+func TestWithRateLimit(t *testing.T) {
+	ctx := context.Background()
+	c := getClient(t, nil, nil, xkcd.WithRateLimit(20, 2))
+
+	start := time.Now()
+	for range 6 {
+		_, err := c.GetPost(ctx, 1)
+		require.NoError(t, err, "expected no error")
+	}
+	// The first 2 requests are a burst, the 4 next ones are 50ms apart.
+	assert.GreaterOrEqual(t, time.Since(start), 190*time.Millisecond, "expected requests to be rate limited")
+
+	t.Run("context is honored", func(t *testing.T) {
+		c := getClient(t, nil, nil, xkcd.WithRateLimit(0.1, 1))
+		_, err := c.GetPost(ctx, 1)
+		require.NoError(t, err, "expected no error")
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err = c.GetPost(ctx, 1)
+		assert.ErrorIs(t, err, context.DeadlineExceeded, "expected context error")
+	})
+}
+
+func TestWithMaxConcurrency(t *testing.T) {
+	ctx := context.Background()
+	running := int32(0)
+	maxRunning := int32(0)
+	c := getClient(t, func(r *http.Request) (*http.Response, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		if r.URL.Host == "imgs.xkcd.com" {
+			return getImageResponse(t, "jpg", nil, nil), nil
+		}
+		return sendValidPost(t)
+	}, nil, xkcd.WithMaxConcurrency(2))
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.GetPost(ctx, 1)
+			assert.NoError(t, err, "expected no error")
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxRunning), "expected concurrent requests to be capped")
+
+	t.Run("image requests hold a slot until closed", func(t *testing.T) {
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			if r.URL.Host == "imgs.xkcd.com" {
+				return getImageResponse(t, "jpg", nil, nil), nil
+			}
+			return sendValidPost(t)
+		}, nil, xkcd.WithMaxConcurrency(1))
+		p, err := c.GetPost(ctx, 1)
+		require.NoError(t, err, "expected no error")
+		rdr, err := p.GetImageContent(ctx)
+		require.NoError(t, err, "expected no error")
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err = c.GetPost(timeoutCtx, 1)
+		assert.ErrorIs(t, err, context.DeadlineExceeded, "expected request to wait for the image request")
+
+		require.NoError(t, rdr.Close())
+		_, err = c.GetPost(ctx, 1)
+		assert.NoError(t, err, "expected slot to be released")
+	})
+}
+
+func TestWithMaxImageBytes(t *testing.T) {
+	ctx := context.Background()
+	const pngLength = 54845
+
+	getContent := func(t *testing.T, contentLength int64, opts ...xkcd.ClientOption) (*xkcd.ImageContent, error) {
+		t.Helper()
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			if r.URL.Host == "imgs.xkcd.com" {
+				resp := getImageResponse(t, "png", nil, nil)
+				resp.ContentLength = contentLength
+				return resp, nil
+			}
+			return sendValidPost(t)
+		}, nil, opts...)
+		p, err := c.GetPost(ctx, 1)
+		require.NoError(t, err, "expected no error while getting post")
+		return p.GetImageContent(ctx)
+	}
+
+	t.Run("announced length", func(t *testing.T) {
+		rdr, err := getContent(t, pngLength, xkcd.WithMaxImageBytes(1000))
+		require.Nil(t, rdr, "expected nil reader")
+		assert.ErrorIs(t, err, xkcd.ErrImageTooLarge, "expected error to be ErrImageTooLarge")
+		var limitErr *xkcd.ImageTooLargeError
+		require.True(t, errors.As(err, &limitErr), "expected an ImageTooLargeError")
+		assert.Equal(t, int64(1000), limitErr.MaxBytes, "expected limit in error")
+		assert.Equal(t, "https://imgs.xkcd.com/comics/barrel_cropped_(1).jpg", limitErr.URL, "expected URL in error")
+	})
+
+	t.Run("read length", func(t *testing.T) {
+		rdr, err := getContent(t, -1, xkcd.WithMaxImageBytes(1000))
+		require.NoError(t, err, "expected no error while getting image")
+		defer rdr.Close()
+		data, err := io.ReadAll(rdr)
+		assert.ErrorIs(t, err, xkcd.ErrImageTooLarge, "expected error to be ErrImageTooLarge")
+		assert.Len(t, data, 1000, "expected content to be read up to the limit")
+		_, err = rdr.Read(make([]byte, 10))
+		assert.ErrorIs(t, err, xkcd.ErrImageTooLarge, "expected error to be returned again")
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		rdr, err := getContent(t, -1, xkcd.WithMaxImageBytes(1000), xkcd.WithCache(xkcd.NewMemoryCache()))
+		require.Nil(t, rdr, "expected nil reader")
+		assert.ErrorIs(t, err, xkcd.ErrImageTooLarge, "expected error to be ErrImageTooLarge")
+	})
+
+	t.Run("within limit", func(t *testing.T) {
+		for _, contentLength := range []int64{-1, pngLength} {
+			rdr, err := getContent(t, contentLength, xkcd.WithMaxImageBytes(pngLength))
+			require.NoError(t, err, "expected no error while getting image")
+			data, err := io.ReadAll(rdr)
+			assert.NoError(t, err, "expected read to not return an error")
+			assert.Len(t, data, pngLength, "expected data to be read correctly")
+		}
+	})
+}
+
+func TestWithMaxImagePixels(t *testing.T) {
+	ctx := context.Background()
+	getImage := func(t *testing.T, maxPixels int64) error {
+		t.Helper()
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			if r.URL.Host == "imgs.xkcd.com" {
+				return getImageResponse(t, "png", nil, nil), nil
+			}
+			return sendValidPost(t)
+		}, nil, xkcd.WithMaxImagePixels(maxPixels))
+		p, err := c.GetPost(ctx, 1)
+		require.NoError(t, err, "expected no error while getting post")
+		_, _, err = p.GetImage(ctx)
+		return err
+	}
+
+	info := func() *xkcd.ImageInfo {
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			if r.URL.Host == "imgs.xkcd.com" {
+				return getImageResponse(t, "png", nil, nil), nil
+			}
+			return sendValidPost(t)
+		}, nil)
+		p, err := c.GetPost(ctx, 1)
+		require.NoError(t, err, "expected no error while getting post")
+		info, err := p.GetImageInfo(ctx)
+		require.NoError(t, err, "expected no error while getting image information")
+		return info
+	}()
+	pixels := int64(info.Width * info.Height)
+
+	err := getImage(t, pixels-1)
+	assert.ErrorIs(t, err, xkcd.ErrImageTooLarge, "expected error to be ErrImageTooLarge")
+	var limitErr *xkcd.ImageTooLargeError
+	require.True(t, errors.As(err, &limitErr), "expected an ImageTooLargeError")
+	assert.Equal(t, info.Width, limitErr.Width, "expected width in error")
+	assert.Equal(t, info.Height, limitErr.Height, "expected height in error")
+	assert.Equal(t, pixels-1, limitErr.MaxPixels, "expected limit in error")
+
+	assert.NoError(t, getImage(t, pixels), "expected image within limit to be decoded")
+	assert.NoError(t, getImage(t, 0), "expected image to not be limited")
+}
@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"math/rand/v2"
 	"net/http"
+	"net/netip"
 	"os"
 	"strings"
 	"testing"
@@ -26,6 +27,7 @@ func getClient(
 	t testing.TB,
 	mockCall mockClient,
 	loggerHandler func(ctx context.Context, record slog.Record) error,
+	opts ...xkcd.ClientOption,
 ) *xkcd.Client {
 	t.Helper()
 
@@ -35,17 +37,25 @@ func getClient(
 		}
 	}
 
-	return xkcd.New(
-		xkcd.WithClient(&http.Client{Transport: &mockRoundTripper{
-			mock: mockCall,
-			t:    t,
-		}}),
-		xkcd.WithLogger(slog.New(
-			slogmock.Option{
-				Handle: loggerHandler,
-			}.NewMockHandler(),
-		)),
-	)
+	return xkcd.New(append(
+		[]xkcd.ClientOption{
+			xkcd.WithClient(&http.Client{Transport: &mockRoundTripper{
+				mock: mockCall,
+				t:    t,
+			}}),
+			xkcd.WithLogger(slog.New(
+				slogmock.Option{
+					Handle: loggerHandler,
+				}.NewMockHandler(),
+			)),
+		},
+		opts...,
+	)...)
+}
+
+// allowLoopback permits the default http client of a client to connect to httptest servers.
+func allowLoopback() xkcd.ClientOption {
+	return xkcd.WithAllowedNetworks(netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128"))
 }
 
 func sendValidPost(t testing.TB) (*http.Response, error) {
@@ -75,8 +85,8 @@ func getRandomPost(t testing.TB, errorRead error, errorClose error) (*xkcd.Post,
 		Alt:        randomEmpty(t, faker.Sentence()),
 		Date:       date,
 		Day:        fmt.Sprintf("%d", date.Day()),
-		Img:        faker.URL(),
-		Link:       randomEmpty(t, faker.URL()),
+		Img:        "https://imgs.xkcd.com/comics/" + strings.ToLower(faker.Word()) + ".png",
+		Link:       randomEmpty(t, "https://xkcd.com/"+strings.ToLower(faker.Word())+"/"),
 		Month:      fmt.Sprintf("%d", date.Month()),
 		News:       randomEmpty(t, faker.Paragraph()),
 		Num:        rand.Uint() + 1,
@@ -127,6 +137,9 @@ func getImageResponse(t testing.TB, imgType string, errorRead error, errorClose
 	case "gif":
 		file = "testdata/image.gif"
 		header = "image/gif"
+	case "webp":
+		file = "testdata/image.webp"
+		header = "image/webp"
 	case "audio":
 		header = "audio/mpeg"
 	case "bogus":
@@ -0,0 +1,50 @@
+package xkcd
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DefaultBaseURL is the base URL of xkcd API.
+const DefaultBaseURL = "https://xkcd.com"
+
+// Mirror is a server serving xkcd API, and optionally post images.
+type Mirror struct {
+	// BaseURL is the base URL of the API, posts are fetched from <BaseURL>/<num>/info.0.json.
+	BaseURL string
+	// ImageBaseURL is the base URL of post images, replacing the scheme, host and port of
+	// Post.Img URLs, and prefixing their path.
+	// If empty, images are fetched from Post.Img.
+	ImageBaseURL string
+}
+
+// apiURL returns the URL of an API path on the mirror.
+func (m Mirror) apiURL(path string) string {
+	return strings.TrimSuffix(m.BaseURL, "/") + "/" + path
+}
+
+// imageURL returns the URL of image img on the mirror.
+func (m Mirror) imageURL(img string) (string, error) {
+	if m.ImageBaseURL == "" {
+		return img, nil
+	}
+	base, err := url.Parse(m.ImageBaseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid image base URL: %w", err)
+	}
+	u, err := url.Parse(img)
+	if err != nil {
+		return "", fmt.Errorf("invalid image URL: %w", err)
+	}
+	u.Scheme = base.Scheme
+	u.Host = base.Host
+	u.Path = strings.TrimSuffix(base.Path, "/") + u.Path
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// mirrors returns the primary server of the client followed by its fallback mirrors.
+func (c *Client) mirrors() []Mirror {
+	return append([]Mirror{c.primary}, c.fallbacks...)
+}
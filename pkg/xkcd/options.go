@@ -1,11 +1,17 @@
 package xkcd
 
-import "log/slog"
+import (
+	"log/slog"
+	"net/netip"
+)
 
 // ClientOption is a function that configures a Client.
 type ClientOption func(c *Client)
 
 // WithClient sets the http client for http operations.
+// The client replaces the default one, so its connections and redirects are not checked: only the URLs
+// requested by the client are checked against the allowed hosts. Use a NewHTTPClient client, or a client
+// with equivalent checks, to keep denying internal addresses and redirects to other hosts.
 func WithClient(g HTTPClient) ClientOption {
 	return func(c *Client) {
 		c.defaultClient = g
@@ -18,3 +24,112 @@ func WithLogger(l *slog.Logger) ClientOption {
 		c.logger = l
 	}
 }
+
+// WithBaseURL sets the base URL of the API, which defaults to DefaultBaseURL.
+// Posts are fetched from <baseURL>/<num>/info.0.json.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) {
+		c.primary.BaseURL = baseURL
+	}
+}
+
+// WithImageBaseURL sets the base URL of post images, which replaces the scheme, host and port of
+// Post.Img URLs, and prefixes their path, when fetching images of posts retrieved by the client.
+// By default, images are fetched from Post.Img.
+func WithImageBaseURL(imageBaseURL string) ClientOption {
+	return func(c *Client) {
+		c.primary.ImageBaseURL = imageBaseURL
+	}
+}
+
+// WithMirrors sets fallback mirrors, tried in order when the API or an image server fails with ErrAPIError.
+func WithMirrors(mirrors ...Mirror) ClientOption {
+	return func(c *Client) {
+		c.fallbacks = mirrors
+	}
+}
+
+// WithRetryPolicy sets the retry policy of requests to the API, and to image servers for posts
+// retrieved by the client. By default, requests are not retried, see DefaultRetryPolicy.
+func WithRetryPolicy(p RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retryPolicy = p
+	}
+}
+
+// WithRateLimit limits requests to the API, and to image servers for posts retrieved by the client,
+// to rps requests per second, allowing bursts of burst requests. Retries are limited too.
+// Requests are not limited if rps is not positive.
+func WithRateLimit(rps float64, burst int) ClientOption {
+	return func(c *Client) {
+		c.limiter = nil
+		if rps > 0 {
+			c.limiter = newRateLimiter(rps, burst)
+		}
+	}
+}
+
+// WithMaxConcurrency limits to n the concurrent requests to the API, and to image servers for posts
+// retrieved by the client. An image request is running until its content reader is closed.
+// Requests are not limited if n is not positive.
+func WithMaxConcurrency(n int) ClientOption {
+	return func(c *Client) {
+		c.slots = nil
+		if n > 0 {
+			c.slots = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithCache sets a cache for responses of the API, and of image servers for posts retrieved by the client.
+// Fresh responses are served from the cache, and stale ones are revalidated with conditional requests.
+func WithCache(cache Cache) ClientOption {
+	return func(c *Client) {
+		c.cache = cache
+	}
+}
+
+// WithStrictContentType makes images of posts retrieved by the client only accepted if they are served with
+// exactly the image/jpeg, image/png or image/gif content type, without detecting the format of others.
+func WithStrictContentType(strict bool) ClientOption {
+	return func(c *Client) {
+		c.strictContentType = strict
+	}
+}
+
+// WithMaxImageBytes limits to n bytes the content of images of posts retrieved by the client. Responses
+// announcing a longer content are rejected, and reading more than n bytes of an image fails, with an
+// *ImageTooLargeError. Images are not limited if n is not positive.
+func WithMaxImageBytes(n int64) ClientOption {
+	return func(c *Client) {
+		c.maxImageBytes = n
+	}
+}
+
+// WithMaxImagePixels limits to n the pixels of images of posts retrieved by the client which are decoded.
+// The size of images is checked before decoding them, and larger ones fail with an *ImageTooLargeError.
+// Images are not limited if n is not positive.
+func WithMaxImagePixels(n int64) ClientOption {
+	return func(c *Client) {
+		c.maxImagePixels = n
+	}
+}
+
+// WithAllowedHosts sets the hosts that the client is allowed to request, and that post images and links
+// must be on, which default to DefaultAllowedHosts. Subdomains of allowed hosts, and hosts of the client
+// mirrors, are allowed too. Requests to other hosts fail with ErrHostNotAllowed.
+func WithAllowedHosts(hosts ...string) ClientOption {
+	return func(c *Client) {
+		c.allowedHosts = append([]string{}, hosts...)
+	}
+}
+
+// WithAllowedNetworks permits the default http client to connect to the loopback, private or link-local
+// addresses of the given networks, such as a mirror on a private network. Connections to other
+// such addresses fail with ErrAddressNotAllowed. It has no effect on http clients set with WithClient,
+// which must be created with NewHTTPClient and the same networks to be restricted.
+func WithAllowedNetworks(networks ...netip.Prefix) ClientOption {
+	return func(c *Client) {
+		c.allowedNetworks = networks
+	}
+}
@@ -2,11 +2,16 @@ package xkcd_test
 
 import (
 	"context"
+	"io"
 	"log/slog"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
 )
 
 func TestWithClient(t *testing.T) {
@@ -42,3 +47,83 @@ func TestWithLogger(t *testing.T) {
 	assert.NotNil(t, p, "expected non-nil post")
 	assert.True(t, loggerUser, "expected given logger to be used")
 }
+
+func TestWithBaseURL(t *testing.T) {
+	var requested string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		resp, _ := sendValidPost(t)
+		_, _ = io.Copy(w, resp.Body)
+	}))
+	defer srv.Close()
+	c := xkcd.New(allowLoopback(), xkcd.WithBaseURL(srv.URL+"/"))
+
+	p, err := c.GetPost(context.Background(), 1)
+	assert.NoError(t, err, "expected no error")
+	assert.NotNil(t, p, "expected non-nil post")
+	assert.Equal(t, "/1/info.0.json", requested, "expected post to be fetched from base URL")
+	assert.Equal(t, "https://xkcd.com/1/", p.Link, "expected link not to be rewritten")
+
+	_, err = c.GetLatest(context.Background())
+	assert.NoError(t, err, "expected no error")
+	assert.Equal(t, "/info.0.json", requested, "expected latest post to be fetched from base URL")
+}
+
+func TestWithImageBaseURL(t *testing.T) {
+	var requested string
+	c := getClient(t, func(r *http.Request) (*http.Response, error) {
+		if r.URL.Host == "mirror.example.com" {
+			requested = r.URL.String()
+			return getImageResponse(t, "png", nil, nil), nil
+		}
+		return sendValidPost(t)
+	}, nil, xkcd.WithImageBaseURL("http://mirror.example.com/xkcd/"))
+
+	p, err := c.GetPost(context.Background(), 1)
+	require.NoError(t, err, "expected no error")
+	rdr, err := p.GetImageContent(context.Background())
+	require.NoError(t, err, "expected no error")
+	defer rdr.Close()
+	assert.Equal(t, "https://imgs.xkcd.com/comics/barrel_cropped_(1).jpg", p.Img, "expected post image URL not to be modified")
+	assert.Equal(t, "http://mirror.example.com/xkcd/comics/barrel_cropped_%281%29.jpg", requested, "expected image URL to be rewritten")
+}
+
+func TestWithMirrors(t *testing.T) {
+	var requested []string
+	c := getClient(t, func(r *http.Request) (*http.Response, error) {
+		requested = append(requested, r.URL.Host)
+		switch r.URL.Host {
+		case "xkcd.com", "imgs.xkcd.com":
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil
+		case "api.mirror.example.com":
+			return sendValidPost(t)
+		case "imgs.mirror.example.com":
+			return getImageResponse(t, "jpg", nil, nil), nil
+		}
+		return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody}, nil
+	}, nil, xkcd.WithMirrors(
+		xkcd.Mirror{BaseURL: "https://api.mirror.example.com"},
+		xkcd.Mirror{BaseURL: "https://unused.example.com", ImageBaseURL: "https://imgs.mirror.example.com"},
+	))
+
+	p, err := c.GetPost(context.Background(), 1)
+	require.NoError(t, err, "expected no error")
+	assert.Equal(t, []string{"xkcd.com", "api.mirror.example.com"}, requested, "expected mirror to be tried after failure")
+
+	requested = nil
+	rdr, err := p.GetImageContent(context.Background())
+	require.NoError(t, err, "expected no error")
+	defer rdr.Close()
+	assert.Equal(t, []string{"imgs.xkcd.com", "imgs.mirror.example.com"}, requested, "expected image mirror to be tried after failure")
+
+	t.Run("no such post", func(t *testing.T) {
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			requested = append(requested, r.URL.Host)
+			return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody}, nil
+		}, nil, xkcd.WithMirrors(xkcd.Mirror{BaseURL: "https://api.mirror.example.com"}))
+		requested = nil
+		_, err := c.GetPost(context.Background(), 1)
+		assert.ErrorIs(t, err, xkcd.ErrNoSuchPost, "expected ErrNoSuchPost")
+		assert.Equal(t, []string{"xkcd.com"}, requested, "expected mirrors not to be tried")
+	})
+}
@@ -19,7 +19,7 @@ type Post struct {
 	// Alt is the alternative text for the post image.
 	Alt string `json:"alt"`
 	// Date is the publication date of the post.
-	Date time.Time
+	Date time.Time `json:"date"`
 	// Day is the day of the month of the publication date of the post as string.
 	Day string `json:"day"`
 	// Img is the URL of the post image.
@@ -41,6 +41,7 @@ type Post struct {
 	// Year is the year of the publication date of the post as string.
 	Year string `json:"year"`
 
+	apiClient     *Client
 	defaultClient HTTPClient
 	logger        *slog.Logger
 }
@@ -50,6 +51,8 @@ var (
 	ErrNoSuchPost = errors.New("no such post")
 	// ErrAPIError is returned when xkcd api returned an error.
 	ErrAPIError = errors.New("xkcd API error")
+	// ErrImageTooLarge is returned when an image exceeds the limits of the client, see ImageTooLargeError.
+	ErrImageTooLarge = errors.New("image is too large")
 )
 
 // NewPost returns a new xkcd API client with the provided options.
@@ -60,9 +63,24 @@ func NewPost(defaultClient HTTPClient, logger *slog.Logger) *Post {
 	}
 }
 
+// NewPostWithClient returns a new post bound to apiClient, as posts retrieved by it: the mirrors, retry policy,
+// image limits and allowed hosts of apiClient apply to fetching its image, with defaultClient, or the http client
+// of apiClient if defaultClient is nil. It is NewPost if apiClient is nil.
+func NewPostWithClient(apiClient *Client, defaultClient HTTPClient, logger *slog.Logger) *Post {
+	post := NewPost(defaultClient, logger)
+	if apiClient == nil {
+		return post
+	}
+	post.apiClient = apiClient
+	if post.defaultClient == nil {
+		post.defaultClient = apiClient.defaultClient
+	}
+	return post
+}
+
 // GetLatest retrieves the latest post.
 func (c *Client) GetLatest(ctx context.Context, client ...HTTPClient) (*Post, error) {
-	return c.getPost(ctx, "https://xkcd.com/info.0.json", client...)
+	return c.getPost(ctx, "info.0.json", client...)
 }
 
 // GetPost retrieves the post with the given number.
@@ -70,20 +88,37 @@ func (c *Client) GetPost(ctx context.Context, num uint, client ...HTTPClient) (*
 	if num == 0 {
 		return nil, ErrNoSuchPost
 	}
-	return c.getPost(ctx, fmt.Sprintf("https://xkcd.com/%d/info.0.json", num), client...)
+	return c.getPost(ctx, fmt.Sprintf("%d/info.0.json", num), client...)
+}
+
+// getPost retrieves a post from the API path, trying mirrors in order on ErrAPIError.
+func (c *Client) getPost(ctx context.Context, path string, client ...HTTPClient) (*Post, error) {
+	mirrors := c.mirrors()
+	var err error
+	for k, mirror := range mirrors {
+		var post *Post
+		post, err = c.getPostFrom(ctx, mirror.apiURL(path), client...)
+		if err == nil || !errors.Is(err, ErrAPIError) || ctx.Err() != nil {
+			return post, err
+		}
+		if k < len(mirrors)-1 {
+			c.logger.Warn(
+				"failed to get post, trying next mirror",
+				slog.String("error", err.Error()),
+				slog.String("mirror", mirrors[k+1].BaseURL),
+			)
+		}
+	}
+	return nil, err
 }
 
-func (c *Client) getPost(ctx context.Context, apiURL string, client ...HTTPClient) (*Post, error) {
+func (c *Client) getPostFrom(ctx context.Context, apiURL string, client ...HTTPClient) (*Post, error) {
 	logger := c.logger.With(slog.String("url", apiURL))
 	logger.Debug("fetching post")
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, http.NoBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
 	//nolint: bodyclose // Body is closed in the defer below
-	resp, err := c.getClient(client...).Do(req)
+	resp, err := c.cachedDo(ctx, c.getClient(client...), apiURL, 0, logger)
 	if err != nil {
-		return nil, fmt.Errorf("%w: failed to send request: %w", ErrAPIError, err)
+		return nil, err
 	}
 	defer func(body io.ReadCloser) {
 		_, _ = io.Copy(io.Discard, body)
@@ -93,20 +128,35 @@ func (c *Client) getPost(ctx context.Context, apiURL string, client ...HTTPClien
 	}(resp.Body)
 	logger.Debug("got api response")
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, ErrNoSuchPost
+		return nil, newResponseError(apiURL, resp, "", ErrNoSuchPost)
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%w: status code is %d", ErrAPIError, resp.StatusCode)
+		return nil, newResponseError(apiURL, resp, fmt.Sprintf("status code is %d", resp.StatusCode), nil)
 	}
 
 	var post *Post
 	err = json.NewDecoder(resp.Body).Decode(&post)
 	if err != nil {
-		return nil, fmt.Errorf("%w: failed to decode response: %w", ErrAPIError, err)
+		return nil, newResponseError(apiURL, resp, "failed to decode response", err)
 	}
+	post.apiClient = c
 	post.defaultClient = c.defaultClient
 	post.logger = logger
-	return parsePost(post)
+	if post, err = parsePost(post); err != nil {
+		return nil, err
+	}
+	if err = c.checkPostHosts(post); err != nil {
+		return nil, newResponseError(apiURL, resp, "", err)
+	}
+	return post, nil
+}
+
+// Validate checks the post with the same rules as posts retrieved from the API, and sets its
+// Date from its Day, Month and Year, and its Link if it is empty.
+// It is meant for posts built from another source, such as an export.
+func (p *Post) Validate() error {
+	_, err := parsePost(p)
+	return err
 }
 
 func parsePost(post *Post) (*Post, error) {
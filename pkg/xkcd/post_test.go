@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -246,10 +247,10 @@ func TestClient_GetPost(t *testing.T) {
 
 func checkPostAsExpected(t *testing.T, expected, p *xkcd.Post) {
 	t.Helper()
-	if !cmp.Equal(expected, p, cmpopts.IgnoreFields(xkcd.Post{}, "Link", "defaultClient", "logger")) {
+	if !cmp.Equal(expected, p, cmpopts.IgnoreFields(xkcd.Post{}, "Link", "apiClient", "defaultClient", "logger")) {
 		msg := fmt.Sprintf(
 			"expected post to be correctly parsed: %s",
-			cmp.Diff(expected, p, cmpopts.IgnoreFields(xkcd.Post{}, "Link", "defaultClient", "logger")),
+			cmp.Diff(expected, p, cmpopts.IgnoreFields(xkcd.Post{}, "Link", "apiClient", "defaultClient", "logger")),
 		)
 		assert.Fail(t, msg)
 	}
@@ -257,3 +258,32 @@ func checkPostAsExpected(t *testing.T, expected, p *xkcd.Post) {
 		assert.Equal(t, expected.Link, p.Link, "expected given link to have been kept")
 	}
 }
+
+func TestPost_Validate(t *testing.T) {
+	t.Run("valid post", func(t *testing.T) {
+		post := &xkcd.Post{
+			Num:   1,
+			Day:   "1",
+			Month: "1",
+			Year:  "2006",
+			Img:   "https://imgs.xkcd.com/comics/barrel_cropped_(1).jpg",
+			Title: "Barrel - Part 1",
+		}
+		assert.NoError(t, post.Validate(), "expected no error")
+		assert.Equal(t, time.Date(2006, time.January, 1, 0, 0, 0, 0, time.Local), post.Date, "expected date to be set")
+		assert.Equal(t, "https://xkcd.com/1/", post.Link, "expected default link to be set")
+	})
+
+	t.Run("invalid post", func(t *testing.T) {
+		post := &xkcd.Post{
+			Num:   1,
+			Day:   "1",
+			Month: "13",
+			Year:  "2006",
+			Img:   "https://imgs.xkcd.com/comics/barrel_cropped_(1).jpg",
+		}
+		err := post.Validate()
+		assert.ErrorIs(t, err, xkcd.ErrAPIError, "expected ErrAPIError")
+		assert.ErrorContains(t, err, "invalid value for month: 13", "expected error to be explicit")
+	})
+}
@@ -0,0 +1,105 @@
+package xkcd
+
+import (
+	"context"
+	"fmt"
+	"iter"
+)
+
+// knownMissingPosts are post numbers which were never published.
+var knownMissingPosts = map[uint]bool{
+	404: true,
+}
+
+type postsConfig struct {
+	skip    map[uint]bool
+	workers int
+}
+
+// PostsOption is a function that configures Client.Posts.
+type PostsOption func(c *postsConfig)
+
+// WithWorkers sets how many posts are fetched concurrently, 4 by default.
+func WithWorkers(n int) PostsOption {
+	return func(c *postsConfig) {
+		c.workers = max(n, 1)
+	}
+}
+
+// WithSkip sets post numbers to skip, in addition to posts which were never published like #404.
+func WithSkip(nums ...uint) PostsOption {
+	return func(c *postsConfig) {
+		for _, num := range nums {
+			c.skip[num] = true
+		}
+	}
+}
+
+// Posts returns an iterator over posts in range [from..to], in ascending order, skipping posts
+// which were never published like #404.
+// Posts are fetched concurrently, ahead of the iteration. A post which fails to be fetched is yielded as
+// a nil post with an error, and the iteration goes on unless the caller breaks out of it, which stops
+// fetching posts. The iteration stops after yielding the context error if ctx is done.
+func (c *Client) Posts(ctx context.Context, from, to uint, opts ...PostsOption) iter.Seq2[*Post, error] {
+	cfg := &postsConfig{
+		skip:    map[uint]bool{},
+		workers: 4,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return func(yield func(*Post, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		type result struct {
+			post *Post
+			err  error
+		}
+		fetch := func(num uint) <-chan result {
+			// Buffered, so that fetches complete even if the iteration is stopped.
+			ch := make(chan result, 1)
+			go func() {
+				post, err := c.GetPost(ctx, num)
+				if err != nil {
+					err = fmt.Errorf("failed to get post %d: %w", num, err)
+				}
+				ch <- result{post: post, err: err}
+			}()
+			return ch
+		}
+
+		next := max(from, 1)
+		nextNum := func() (uint, bool) {
+			for ; next <= to; next++ {
+				if !knownMissingPosts[next] && !cfg.skip[next] {
+					next++
+					return next - 1, true
+				}
+			}
+			return 0, false
+		}
+		var pending []<-chan result
+		for len(pending) < cfg.workers {
+			num, ok := nextNum()
+			if !ok {
+				break
+			}
+			pending = append(pending, fetch(num))
+		}
+		for len(pending) > 0 {
+			res := <-pending[0]
+			pending = pending[1:]
+			if ctx.Err() != nil {
+				yield(nil, ctx.Err())
+				return
+			}
+			if num, ok := nextNum(); ok {
+				pending = append(pending, fetch(num))
+			}
+			if !yield(res.post, res.err) {
+				return
+			}
+		}
+	}
+}
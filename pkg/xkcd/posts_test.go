@@ -0,0 +1,135 @@
+package xkcd_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+// sendPostNum sends a valid post with the number requested, after a random delay.
+func sendPostNum(t testing.TB, r *http.Request) (*http.Response, error) {
+	t.Helper()
+	num, err := strconv.Atoi(strings.Split(strings.Trim(r.URL.Path, "/"), "/")[0])
+	require.NoError(t, err, "expected a post number in request path")
+	time.Sleep(time.Duration(rand.IntN(5)) * time.Millisecond)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body: io.NopCloser(strings.NewReader(fmt.Sprintf(
+			`{"month": "1", "num": %d, "link": "", "year": "2006", "news": "", "safe_title": "Post", "transcript": "", "alt": "Alt", "img": "https://imgs.xkcd.com/comics/post.png", "title": "Post", "day": "1"}`,
+			num,
+		))),
+	}, nil
+}
+
+func TestClient_Posts(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("posts are yielded in order", func(t *testing.T) {
+		requested := int32(0)
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&requested, 1)
+			return sendPostNum(t, r)
+		}, nil)
+		var nums []uint
+		for p, err := range c.Posts(ctx, 400, 410, xkcd.WithWorkers(3)) {
+			require.NoError(t, err, "expected no error")
+			nums = append(nums, p.Num)
+		}
+		assert.Equal(t, []uint{400, 401, 402, 403, 405, 406, 407, 408, 409, 410}, nums, "expected posts in order without #404")
+		assert.Equal(t, int32(10), atomic.LoadInt32(&requested), "expected #404 not to be requested")
+	})
+
+	t.Run("skipped posts are not requested", func(t *testing.T) {
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			return sendPostNum(t, r)
+		}, nil)
+		var nums []uint
+		for p, err := range c.Posts(ctx, 0, 5, xkcd.WithSkip(2, 4)) {
+			require.NoError(t, err, "expected no error")
+			nums = append(nums, p.Num)
+		}
+		assert.Equal(t, []uint{1, 3, 5}, nums, "expected skipped posts not to be yielded")
+	})
+
+	t.Run("empty range", func(t *testing.T) {
+		c := getClient(t, nil, nil)
+		for range c.Posts(ctx, 10, 9) {
+			assert.Fail(t, "expected no post")
+		}
+	})
+
+	t.Run("errors are yielded", func(t *testing.T) {
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			if strings.HasPrefix(r.URL.Path, "/2/") {
+				return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody}, nil
+			}
+			return sendPostNum(t, r)
+		}, nil)
+		var nums []uint
+		var errs []error
+		for p, err := range c.Posts(ctx, 1, 3) {
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			nums = append(nums, p.Num)
+		}
+		assert.Equal(t, []uint{1, 3}, nums, "expected other posts to be yielded")
+		require.Len(t, errs, 1, "expected an error")
+		assert.ErrorIs(t, errs[0], xkcd.ErrNoSuchPost, "expected no such post error")
+		assert.ErrorContains(t, errs[0], "post 2", "expected error to mention the post number")
+	})
+
+	t.Run("breaking stops fetching", func(t *testing.T) {
+		requested := int32(0)
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&requested, 1)
+			return sendPostNum(t, r)
+		}, nil)
+		var nums []uint
+		for p, err := range c.Posts(ctx, 1, 1000, xkcd.WithWorkers(2)) {
+			require.NoError(t, err, "expected no error")
+			nums = append(nums, p.Num)
+			if len(nums) == 3 {
+				break
+			}
+		}
+		assert.Equal(t, []uint{1, 2, 3}, nums, "expected iteration to stop")
+		assert.LessOrEqual(t, atomic.LoadInt32(&requested), int32(5), "expected fetching to stop")
+	})
+
+	t.Run("context is honored", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			return sendPostNum(t, r)
+		}, nil)
+		var errs []error
+		count := 0
+		for p, err := range c.Posts(ctx, 1, 1000) {
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			count++
+			if p.Num == 2 {
+				cancel()
+			}
+		}
+		assert.Equal(t, 2, count, "expected iteration to stop once context is canceled")
+		require.Len(t, errs, 1, "expected a single error")
+		assert.ErrorIs(t, errs[0], context.Canceled, "expected context error")
+	})
+}
@@ -0,0 +1,44 @@
+package xkcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+)
+
+// randomMaxAttempts is the number of posts GetRandom tries before giving up, if the picked ones do not exist.
+const randomMaxAttempts = 5
+
+// GetRandom retrieves a post picked uniformly among all published posts, up to the latest one.
+// Posts which were never published, like #404, are never picked.
+func (c *Client) GetRandom(ctx context.Context, client ...HTTPClient) (*Post, error) {
+	latest, err := c.GetLatest(ctx, client...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest post: %w", err)
+	}
+	for range randomMaxAttempts {
+		num := randomNum(latest.Num)
+		if num == latest.Num {
+			return latest, nil
+		}
+		post, err := c.GetPost(ctx, num, client...)
+		if errors.Is(err, ErrNoSuchPost) {
+			c.logger.Debug("picked post does not exist, picking another one", slog.Uint64("num", uint64(num)))
+			continue
+		}
+		return post, err
+	}
+	return nil, fmt.Errorf("%w: no existing post picked after %d attempts", ErrNoSuchPost, randomMaxAttempts)
+}
+
+// randomNum returns a number picked uniformly in range [1..latest], except posts which were never published.
+func randomNum(latest uint) uint {
+	for {
+		num := rand.N(latest) + 1
+		if !knownMissingPosts[num] {
+			return num
+		}
+	}
+}
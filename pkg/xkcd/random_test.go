@@ -0,0 +1,76 @@
+package xkcd_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+func TestClient_GetRandom(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("posts are picked up to the latest one", func(t *testing.T) {
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			if r.URL.Path == "/info.0.json" {
+				r.URL.Path = "/5/info.0.json"
+			}
+			return sendPostNum(t, r)
+		}, nil)
+		picked := map[uint]bool{}
+		for range 100 {
+			post, err := c.GetRandom(ctx)
+			require.NoError(t, err, "expected no error")
+			require.NotNil(t, post, "expected a post")
+			picked[post.Num] = true
+		}
+		assert.Equal(t, map[uint]bool{1: true, 2: true, 3: true, 4: true, 5: true}, picked, "expected all posts to be picked")
+	})
+
+	t.Run("missing posts are never picked", func(t *testing.T) {
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			if r.URL.Path == "/info.0.json" {
+				r.URL.Path = "/405/info.0.json"
+			}
+			if r.URL.Path == "/404/info.0.json" {
+				assert.Fail(t, "expected #404 not to be requested")
+			}
+			return sendPostNum(t, r)
+		}, nil)
+		for range 100 {
+			post, err := c.GetRandom(ctx)
+			require.NoError(t, err, "expected no error")
+			assert.NotEqual(t, uint(404), post.Num, "expected #404 not to be picked")
+		}
+	})
+
+	t.Run("posts not found are skipped", func(t *testing.T) {
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			if r.URL.Path == "/info.0.json" {
+				r.URL.Path = "/50/info.0.json"
+			}
+			if strings.HasPrefix(r.URL.Path, "/1/") {
+				return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody}, nil
+			}
+			return sendPostNum(t, r)
+		}, nil)
+		for range 20 {
+			post, err := c.GetRandom(ctx)
+			require.NoError(t, err, "expected no error")
+			assert.NotEqual(t, uint(1), post.Num, "expected post not found not to be returned")
+		}
+	})
+
+	t.Run("latest post error", func(t *testing.T) {
+		c := getClient(t, func(_ *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusInternalServerError, Body: http.NoBody}, nil
+		}, nil)
+		_, err := c.GetRandom(ctx)
+		assert.ErrorIs(t, err, xkcd.ErrAPIError, "expected API error")
+	})
+}
@@ -0,0 +1,152 @@
+package xkcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy defines how requests to the API and to image servers are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a request, including the first one.
+	// Requests are not retried if it is lower than 2.
+	MaxAttempts uint
+	// BaseDelay is the delay before the first retry, doubled after each retry.
+	BaseDelay time.Duration
+	// MaxDelay is the maximum delay between two attempts, unless the server asks for a longer one
+	// with a Retry-After header.
+	MaxDelay time.Duration
+	// Jitter is the fraction of the delay, between 0 and 1, which is randomly added or removed
+	// from each delay, to spread retries of concurrent requests.
+	Jitter float64
+	// RetryableStatuses are the response status codes which are retried.
+	// Network errors are always retried.
+	RetryableStatuses []int
+}
+
+// DefaultRetryPolicy returns a retry policy making up to 4 attempts, from 500ms to 30s apart, and
+// retrying 429 Too Many Requests and 5xx server errors responses, and network errors.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.2,
+		RetryableStatuses: []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// delay returns the delay before the attempt following the given one, and the response of the given attempt.
+func (p RetryPolicy) delay(attempt uint, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return d
+		}
+	}
+	d := p.BaseDelay << min(attempt-1, 32)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d += time.Duration(float64(d) * p.Jitter * (rand.Float64()*2 - 1))
+	}
+	return max(d, 0)
+}
+
+// retryAfter parses a Retry-After header value, either a number of seconds or a HTTP date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// retryable returns true if the response or error of an attempt should be retried.
+func (p RetryPolicy) retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil &&
+			!errors.Is(err, context.Canceled) &&
+			!errors.Is(err, context.DeadlineExceeded) &&
+			!errors.Is(err, ErrAddressNotAllowed) &&
+			!errors.Is(err, ErrHostNotAllowed)
+	}
+	return slices.Contains(p.RetryableStatuses, resp.StatusCode)
+}
+
+// do sends a request with method to rawURL with client and the given header, and retries it with the retry policy of c.
+// It returns the response of the last attempt, which status code must be checked by the caller,
+// an error wrapping ErrHostNotAllowed if the host of rawURL is not allowed by c,
+// or an *APIError if it failed to be sent.
+func (c *Client) do(
+	ctx context.Context,
+	client HTTPClient,
+	method string,
+	rawURL string,
+	header http.Header,
+	logger *slog.Logger,
+) (*http.Response, error) {
+	if err := c.checkHost(rawURL); err != nil {
+		return nil, err
+	}
+	policy := c.retryPolicy
+	for attempt := uint(1); ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, http.NoBody)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		logger.Debug("sending request", slog.Uint64("attempt", uint64(attempt)))
+		resp, err := c.send(client, req)
+		if attempt >= policy.MaxAttempts || !policy.retryable(ctx, resp, err) {
+			return sendResult(rawURL, resp, err)
+		}
+		delay := policy.delay(attempt, resp)
+		if dl, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(dl) {
+			logger.Debug("not retrying request, context deadline would be exceeded", slog.Duration("delay", delay))
+			return sendResult(rawURL, resp, err)
+		}
+		log := logger.With(slog.Uint64("attempt", uint64(attempt)), slog.Duration("delay", delay))
+		if err != nil {
+			log.Warn("request failed, retrying", slog.String("error", err.Error()))
+		} else {
+			log.Warn("request failed, retrying", slog.Int("status", resp.StatusCode))
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, &APIError{URL: rawURL, Message: "failed to send request", Err: ctx.Err()}
+		case <-timer.C:
+		}
+	}
+}
+
+func sendResult(rawURL string, resp *http.Response, err error) (*http.Response, error) {
+	if err != nil {
+		return nil, &APIError{URL: rawURL, Message: "failed to send request", Err: err}
+	}
+	return resp, nil
+}
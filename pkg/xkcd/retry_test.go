@@ -0,0 +1,144 @@
+package xkcd_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+func testRetryPolicy() xkcd.RetryPolicy {
+	p := xkcd.DefaultRetryPolicy()
+	p.BaseDelay = time.Millisecond
+	p.MaxDelay = 10 * time.Millisecond
+	return p
+}
+
+func statusResponse(status int, header ...string) *http.Response {
+	hdr := http.Header{}
+	for k := 0; k+1 < len(header); k += 2 {
+		hdr.Set(header[k], header[k+1])
+	}
+	return &http.Response{StatusCode: status, Header: hdr, Body: http.NoBody}
+}
+
+func TestWithRetryPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("transient errors are retried", func(t *testing.T) {
+		attempts := 0
+		retriesLogged := int32(0)
+		c := getClient(t, func(_ *http.Request) (*http.Response, error) {
+			attempts++
+			switch attempts {
+			case 1:
+				return statusResponse(http.StatusServiceUnavailable), nil
+			case 2:
+				return nil, errors.New("connection reset by peer")
+			}
+			return sendValidPost(t)
+		}, func(_ context.Context, record slog.Record) error {
+			if record.Message == "request failed, retrying" {
+				atomic.AddInt32(&retriesLogged, 1)
+			}
+			return nil
+		}, xkcd.WithRetryPolicy(testRetryPolicy()))
+		p, err := c.GetPost(ctx, 1)
+		assert.NoError(t, err, "expected no error")
+		assert.NotNil(t, p, "expected non-nil post")
+		assert.Equal(t, 3, attempts, "expected request to be retried")
+		assert.Equal(t, int32(2), atomic.LoadInt32(&retriesLogged), "expected retries to be logged")
+	})
+
+	t.Run("attempts are limited", func(t *testing.T) {
+		attempts := 0
+		c := getClient(t, func(_ *http.Request) (*http.Response, error) {
+			attempts++
+			return statusResponse(http.StatusBadGateway), nil
+		}, nil, xkcd.WithRetryPolicy(testRetryPolicy()))
+		_, err := c.GetPost(ctx, 1)
+		assert.ErrorIs(t, err, xkcd.ErrAPIError, "expected ErrAPIError")
+		assert.ErrorContains(t, err, "status code is 502", "expected last status code")
+		assert.Equal(t, 4, attempts, "expected max attempts to be made")
+	})
+
+	t.Run("other statuses are not retried", func(t *testing.T) {
+		attempts := 0
+		c := getClient(t, func(_ *http.Request) (*http.Response, error) {
+			attempts++
+			return statusResponse(http.StatusNotFound), nil
+		}, nil, xkcd.WithRetryPolicy(testRetryPolicy()))
+		_, err := c.GetPost(ctx, 1)
+		assert.ErrorIs(t, err, xkcd.ErrNoSuchPost, "expected ErrNoSuchPost")
+		assert.Equal(t, 1, attempts, "expected request not to be retried")
+	})
+
+	t.Run("Retry-After is honored", func(t *testing.T) {
+		attempts := 0
+		c := getClient(t, func(_ *http.Request) (*http.Response, error) {
+			attempts++
+			if attempts == 1 {
+				return statusResponse(http.StatusTooManyRequests, "Retry-After", "1"), nil
+			}
+			return sendValidPost(t)
+		}, nil, xkcd.WithRetryPolicy(testRetryPolicy()))
+		start := time.Now()
+		_, err := c.GetPost(ctx, 1)
+		assert.NoError(t, err, "expected no error")
+		assert.GreaterOrEqual(t, time.Since(start), time.Second, "expected Retry-After delay to be waited")
+	})
+
+	t.Run("context deadline is honored", func(t *testing.T) {
+		attempts := 0
+		c := getClient(t, func(_ *http.Request) (*http.Response, error) {
+			attempts++
+			return statusResponse(http.StatusTooManyRequests, "Retry-After", "3600"), nil
+		}, nil, xkcd.WithRetryPolicy(testRetryPolicy()))
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		start := time.Now()
+		_, err := c.GetPost(ctx, 1)
+		assert.ErrorContains(t, err, "status code is 429", "expected last status code")
+		assert.Equal(t, 1, attempts, "expected request not to be retried after deadline")
+		assert.Less(t, time.Since(start), time.Second, "expected not to wait for the deadline")
+	})
+
+	t.Run("image requests are retried", func(t *testing.T) {
+		imageAttempts := 0
+		c := getClient(t, func(r *http.Request) (*http.Response, error) {
+			if r.URL.Host != "imgs.xkcd.com" {
+				return sendValidPost(t)
+			}
+			imageAttempts++
+			if imageAttempts == 1 {
+				return statusResponse(http.StatusServiceUnavailable), nil
+			}
+			return getImageResponse(t, "jpg", nil, nil), nil
+		}, nil, xkcd.WithRetryPolicy(testRetryPolicy()))
+		p, err := c.GetPost(ctx, 1)
+		require.NoError(t, err, "expected no error")
+		rdr, err := p.GetImageContent(ctx)
+		require.NoError(t, err, "expected no error")
+		defer rdr.Close()
+		assert.Equal(t, 2, imageAttempts, "expected image request to be retried")
+	})
+
+	t.Run("no retry by default", func(t *testing.T) {
+		attempts := 0
+		c := getClient(t, func(_ *http.Request) (*http.Response, error) {
+			attempts++
+			return statusResponse(http.StatusServiceUnavailable), nil
+		}, nil)
+		_, err := c.GetPost(ctx, 1)
+		assert.ErrorIs(t, err, xkcd.ErrAPIError, "expected ErrAPIError")
+		assert.Equal(t, 1, attempts, "expected a single attempt")
+	})
+}
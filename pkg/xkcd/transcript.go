@@ -0,0 +1,230 @@
+package xkcd
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// TranscriptElementKind is the kind of a transcript element.
+type TranscriptElementKind string
+
+const (
+	// TranscriptScene is a scene description, written [[...]] or ((...)).
+	TranscriptScene TranscriptElementKind = "scene"
+	// TranscriptDialogue is a line said by a speaker, written Speaker: line.
+	TranscriptDialogue TranscriptElementKind = "dialogue"
+	// TranscriptTitleText is the title text of the post, written {{Title text: ...}} or {{Alt: ...}}.
+	TranscriptTitleText TranscriptElementKind = "title_text"
+	// TranscriptText is any other text, such as captions or narration.
+	TranscriptText TranscriptElementKind = "text"
+)
+
+// maxSpeakerLength is the maximum length of a dialogue speaker, longer prefixes are not considered speakers.
+const maxSpeakerLength = 40
+
+// titleTextLabels are the labels of title text blocks, lower cased.
+var titleTextLabels = []string{"title text", "title-text", "alt text", "alt-text", "alt"}
+
+// Transcript is a parsed post transcript.
+type Transcript struct {
+	// Panels are the panels of the post, in reading order.
+	Panels []TranscriptPanel `json:"panels"`
+}
+
+// TranscriptPanel is a panel of a transcript.
+type TranscriptPanel struct {
+	// Elements are the elements of the panel, in reading order.
+	Elements []TranscriptElement `json:"elements"`
+}
+
+// TranscriptElement is an element of a transcript panel.
+type TranscriptElement struct {
+	// Kind is the kind of element.
+	Kind TranscriptElementKind `json:"kind"`
+	// Speaker is the speaker of a dialogue line, empty for other kinds.
+	Speaker string `json:"speaker,omitempty"`
+	// Text is the text of the element, without its markup.
+	Text string `json:"text"`
+}
+
+// TitleText returns the text of the first title text block of the transcript, or an empty string if there is none.
+func (t *Transcript) TitleText() string {
+	for _, panel := range t.Panels {
+		for _, elem := range panel.Elements {
+			if elem.Kind == TranscriptTitleText {
+				return elem.Text
+			}
+		}
+	}
+	return ""
+}
+
+// Speakers returns the speakers of the transcript dialogue lines, in order of first appearance.
+func (t *Transcript) Speakers() []string {
+	var speakers []string
+	seen := map[string]bool{}
+	for _, panel := range t.Panels {
+		for _, elem := range panel.Elements {
+			if elem.Kind == TranscriptDialogue && !seen[elem.Speaker] {
+				seen[elem.Speaker] = true
+				speakers = append(speakers, elem.Speaker)
+			}
+		}
+	}
+	return speakers
+}
+
+// ParseTranscript parses a transcript written with xkcd conventions: [[scene descriptions]],
+// Speaker: line, {{Title text: ...}} blocks, and panels separated by blank lines or lines of dashes.
+// It never fails: markup which is not closed in its panel is closed at the end of its line, and anything
+// not recognized is kept as TranscriptText.
+func ParseTranscript(transcript string) *Transcript {
+	p := &transcriptParser{
+		lines:      strings.Split(strings.ReplaceAll(transcript, "\r\n", "\n"), "\n"),
+		transcript: &Transcript{Panels: []TranscriptPanel{}},
+	}
+	p.parse()
+	return p.transcript
+}
+
+// ParsedTranscript parses the post transcript, see ParseTranscript.
+func (p *Post) ParsedTranscript() *Transcript {
+	return ParseTranscript(p.Transcript)
+}
+
+type transcriptParser struct {
+	lines      []string
+	panel      *TranscriptPanel
+	transcript *Transcript
+}
+
+func (p *transcriptParser) parse() {
+	for k := 0; k < len(p.lines); k++ {
+		line := strings.TrimSpace(p.lines[k])
+		if line == "" || isPanelSeparator(line) {
+			p.endPanel()
+			continue
+		}
+		for line != "" {
+			var rest string
+			rest, k = p.parseLine(line, k)
+			line = strings.TrimSpace(rest)
+		}
+	}
+	p.endPanel()
+}
+
+// parseLine parses the first element of line, which is the line k of the transcript, and returns what
+// follows it on the same line and the index of the last line it spans.
+func (p *transcriptParser) parseLine(line string, k int) (string, int) {
+	for _, markup := range []struct {
+		open, close string
+	}{
+		{"[[", "]]"},
+		{"((", "))"},
+		{"{{", "}}"},
+	} {
+		if !strings.HasPrefix(line, markup.open) {
+			continue
+		}
+		text, rest, last := p.block(line[len(markup.open):], k, markup.close)
+		if markup.open == "{{" {
+			p.addBlock(text)
+		} else {
+			p.add(TranscriptElement{Kind: TranscriptScene, Text: text})
+		}
+		return rest, last
+	}
+	// Dialogue lines run up to the end of the line, with any markup they contain.
+	if speaker, said, ok := splitDialogue(line); ok {
+		p.add(TranscriptElement{Kind: TranscriptDialogue, Speaker: speaker, Text: said})
+		return "", k
+	}
+	// Other text runs up to the next markup on the line.
+	end := len(line)
+	for _, open := range []string{"[[", "((", "{{"} {
+		if n := strings.Index(line, open); n > 0 && n < end {
+			end = n
+		}
+	}
+	p.add(TranscriptElement{Kind: TranscriptText, Text: strings.TrimSpace(line[:end])})
+	return line[end:], k
+}
+
+// block returns the text of a block starting with s on line k up to closing, what follows it, and the index of
+// its last line. A block which is not closed before the end of the panel ends with line k.
+func (p *transcriptParser) block(s string, k int, closing string) (string, string, int) {
+	if n := strings.Index(s, closing); n >= 0 {
+		return strings.TrimSpace(s[:n]), s[n+len(closing):], k
+	}
+	parts := []string{strings.TrimSpace(s)}
+	for last := k + 1; last < len(p.lines); last++ {
+		line := strings.TrimSpace(p.lines[last])
+		if line == "" {
+			break
+		}
+		if n := strings.Index(line, closing); n >= 0 {
+			parts = append(parts, strings.TrimSpace(line[:n]))
+			return strings.Join(parts, " "), line[n+len(closing):], last
+		}
+		parts = append(parts, line)
+	}
+	return strings.TrimSpace(s), "", k
+}
+
+// addBlock adds a {{...}} block, which is a title text block if it has a title text label.
+func (p *transcriptParser) addBlock(text string) {
+	label, value, ok := strings.Cut(text, ":")
+	if ok && isTitleTextLabel(label) {
+		p.add(TranscriptElement{Kind: TranscriptTitleText, Text: strings.TrimSpace(value)})
+		return
+	}
+	p.add(TranscriptElement{Kind: TranscriptText, Text: text})
+}
+
+func (p *transcriptParser) add(elem TranscriptElement) {
+	if elem.Text == "" && elem.Kind != TranscriptDialogue {
+		return
+	}
+	if p.panel == nil {
+		p.panel = &TranscriptPanel{}
+	}
+	p.panel.Elements = append(p.panel.Elements, elem)
+}
+
+func (p *transcriptParser) endPanel() {
+	if p.panel != nil {
+		p.transcript.Panels = append(p.transcript.Panels, *p.panel)
+		p.panel = nil
+	}
+}
+
+func isTitleTextLabel(label string) bool {
+	return slices.Contains(titleTextLabels, strings.ToLower(strings.TrimSpace(label)))
+}
+
+// isPanelSeparator returns true if line is only made of at least three dashes, equal signs or underscores.
+func isPanelSeparator(line string) bool {
+	if len(line) < 3 {
+		return false
+	}
+	return strings.Trim(line, "-=_") == ""
+}
+
+// splitDialogue splits a dialogue line into its speaker and what is said.
+func splitDialogue(line string) (string, string, bool) {
+	speaker, said, ok := strings.Cut(line, ":")
+	speaker = strings.TrimSpace(speaker)
+	if !ok || speaker == "" || len(speaker) > maxSpeakerLength {
+		return "", "", false
+	}
+	// Times such as 10:30 and URLs are not dialogue lines.
+	if said != "" && !unicode.IsSpace(rune(said[0])) {
+		return "", "", false
+	}
+	if !strings.ContainsFunc(speaker, unicode.IsLetter) || strings.ContainsAny(speaker, "[]{}\"") {
+		return "", "", false
+	}
+	return speaker, strings.TrimSpace(said), true
+}
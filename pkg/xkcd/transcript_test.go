@@ -0,0 +1,128 @@
+package xkcd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+func scene(text string) xkcd.TranscriptElement {
+	return xkcd.TranscriptElement{Kind: xkcd.TranscriptScene, Text: text}
+}
+
+func dialogue(speaker, text string) xkcd.TranscriptElement {
+	return xkcd.TranscriptElement{Kind: xkcd.TranscriptDialogue, Speaker: speaker, Text: text}
+}
+
+func text(text string) xkcd.TranscriptElement {
+	return xkcd.TranscriptElement{Kind: xkcd.TranscriptText, Text: text}
+}
+
+func titleText(text string) xkcd.TranscriptElement {
+	return xkcd.TranscriptElement{Kind: xkcd.TranscriptTitleText, Text: text}
+}
+
+func panels(elements ...[]xkcd.TranscriptElement) []xkcd.TranscriptPanel {
+	result := []xkcd.TranscriptPanel{}
+	for _, e := range elements {
+		result = append(result, xkcd.TranscriptPanel{Elements: e})
+	}
+	return result
+}
+
+func TestParseTranscript(t *testing.T) {
+	cases := []struct {
+		name       string
+		transcript string
+		expected   []xkcd.TranscriptPanel
+	}{
+		{
+			name:       "empty",
+			transcript: " \n\n",
+			expected:   panels(),
+		},
+		{
+			name:       "single panel",
+			transcript: "[[A boy sits in a barrel which is floating in an ocean.]]\nBoy: I wonder where I'll float next?\n[[The barrel drifts into the distance. Nothing else can be seen.]]\n{{Alt: Don't we all.}}",
+			expected: panels([]xkcd.TranscriptElement{
+				scene("A boy sits in a barrel which is floating in an ocean."),
+				dialogue("Boy", "I wonder where I'll float next?"),
+				scene("The barrel drifts into the distance. Nothing else can be seen."),
+				titleText("Don't we all."),
+			}),
+		},
+		{
+			name:       "panel breaks",
+			transcript: "[[Panel one.]]\nCueball: Hi.\n\n\n[[Panel two.]]\n----\n((Panel three.))\n{{Title text: Hello.}}",
+			expected: panels(
+				[]xkcd.TranscriptElement{scene("Panel one."), dialogue("Cueball", "Hi.")},
+				[]xkcd.TranscriptElement{scene("Panel two.")},
+				[]xkcd.TranscriptElement{scene("Panel three."), titleText("Hello.")},
+			),
+		},
+		{
+			name:       "several elements on a line",
+			transcript: "[[Cueball walks in.]] Caption text {{Title-text: Tt.}}",
+			expected: panels([]xkcd.TranscriptElement{
+				scene("Cueball walks in."),
+				text("Caption text"),
+				titleText("Tt."),
+			}),
+		},
+		{
+			name:       "multi-line blocks",
+			transcript: "[[A long\nscene description.]] Megan: Hey.\n{{Title text:\nMulti-line title text.}}",
+			expected: panels([]xkcd.TranscriptElement{
+				scene("A long scene description."),
+				dialogue("Megan", "Hey."),
+				titleText("Multi-line title text."),
+			}),
+		},
+		{
+			name:       "dialogue lines",
+			transcript: "Cueball (thinking): Hmm: [[pause]] yes.\nBlack Hat:\nSign: 10:30 closed\nThe time is 10:30\nSee http://xkcd.com\n\"Quote\": not a speaker",
+			expected: panels([]xkcd.TranscriptElement{
+				dialogue("Cueball (thinking)", "Hmm: [[pause]] yes."),
+				dialogue("Black Hat", ""),
+				dialogue("Sign", "10:30 closed"),
+				text("The time is 10:30"),
+				text("See http://xkcd.com"),
+				text("\"Quote\": not a speaker"),
+			}),
+		},
+		{
+			name:       "malformed markup",
+			transcript: "[[Unclosed scene\nPerson: line\n\n{{Not a title text}}\n]] stray ((\n{{Alt:}}",
+			expected: panels(
+				[]xkcd.TranscriptElement{scene("Unclosed scene"), dialogue("Person", "line")},
+				[]xkcd.TranscriptElement{text("Not a title text"), text("]] stray")},
+			),
+		},
+		{
+			name:       "windows line endings",
+			transcript: "[[Scene.]]\r\nMegan: Hi.\r\n\r\nCueball: Bye.",
+			expected: panels(
+				[]xkcd.TranscriptElement{scene("Scene."), dialogue("Megan", "Hi.")},
+				[]xkcd.TranscriptElement{dialogue("Cueball", "Bye.")},
+			),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, xkcd.ParseTranscript(c.transcript).Panels, "expected transcript to be parsed")
+		})
+	}
+}
+
+func TestTranscript_TitleText(t *testing.T) {
+	transcript := xkcd.ParseTranscript("Megan: Hi.\n\n{{Title text: First.}}\n{{Alt: Second.}}")
+	assert.Equal(t, "First.", transcript.TitleText(), "expected first title text")
+	assert.Empty(t, xkcd.ParseTranscript("Megan: Hi.").TitleText(), "expected no title text")
+}
+
+func TestTranscript_Speakers(t *testing.T) {
+	transcript := xkcd.ParseTranscript("Megan: Hi.\nCueball: Hi.\n\nMegan: Bye.\nWhite Hat: Bye.")
+	assert.Equal(t, []string{"Megan", "Cueball", "White Hat"}, transcript.Speakers(), "expected speakers in order")
+}
@@ -3,24 +3,41 @@ package xkcd
 
 import (
 	"log/slog"
-	"net/http"
+	"net/netip"
 )
 
 // Client is a xkcd api client.
 type Client struct {
-	defaultClient HTTPClient
-	logger        *slog.Logger
+	allowedHosts      []string
+	allowedNetworks   []netip.Prefix
+	cache             Cache
+	defaultClient     HTTPClient
+	fallbacks         []Mirror
+	limiter           *rateLimiter
+	logger            *slog.Logger
+	maxImageBytes     int64
+	maxImagePixels    int64
+	primary           Mirror
+	retryPolicy       RetryPolicy
+	slots             chan struct{}
+	strictContentType bool
 }
 
 // New returns a new xkcd API client with the provided options.
+// Unless WithClient is used, its http client is a NewHTTPClient one following redirects only to the hosts
+// allowed by the client, see WithAllowedHosts and WithAllowedNetworks, and not using proxies.
 func New(opts ...ClientOption) *Client {
 	client := &Client{
-		defaultClient: &http.Client{},
-		logger:        slog.New(slog.NewTextHandler(nullWriter{}, nil)),
+		allowedHosts: DefaultAllowedHosts(),
+		logger:       slog.New(slog.NewTextHandler(nullWriter{}, nil)),
+		primary:      Mirror{BaseURL: DefaultBaseURL},
 	}
 	for _, opt := range opts {
 		opt(client)
 	}
+	if client.defaultClient == nil {
+		client.defaultClient = newHTTPClient(client.checkHost, client.allowedNetworks)
+	}
 	return client
 }
 
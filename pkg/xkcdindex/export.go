@@ -0,0 +1,252 @@
+package xkcdindex
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+// Format is an index export and import format.
+type Format string
+
+const (
+	// FormatNDJSON is a JSON Lines format, holding one xkcd.Post JSON record per line.
+	FormatNDJSON Format = "ndjson"
+	// FormatCSV is a CSV format holding posts metadata, with a header line naming the columns.
+	FormatCSV Format = "csv"
+	// FormatTar is a tar archive holding posts metadata in NDJSON format in a posts.ndjson file, followed
+	// by images stored in index, in an images directory, and named by post number.
+	FormatTar Format = "tar"
+	// FormatZip is a zip archive holding the same files as FormatTar.
+	FormatZip Format = "zip"
+)
+
+const (
+	archivePostsFile = "posts.ndjson"
+	archiveImagesDir = "images"
+)
+
+// csvColumns are the columns of FormatCSV.
+var csvColumns = []string{"num", "title", "date", "link", "image", "alt_text", "transcript", "news"}
+
+// ParseFormat returns the format with the given name.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case FormatNDJSON, FormatCSV, FormatTar, FormatZip:
+		return f, nil
+	case "jsonl":
+		return FormatNDJSON, nil
+	}
+	return "", fmt.Errorf("unsupported format %q, expected one of ndjson, csv, tar or zip", name)
+}
+
+// FormatFromPath returns the format matching the extension of a file path.
+func FormatFromPath(p string) (Format, error) {
+	return ParseFormat(strings.TrimPrefix(path.Ext(p), "."))
+}
+
+// Export writes all indexed posts to w in the given format, and returns how many posts were written.
+func (i *Index) Export(ctx context.Context, w io.Writer, format Format) (uint, error) {
+	if i.db == nil {
+		return 0, errors.New("index is not initialized")
+	}
+	logger := i.logger.With(slog.String("format", string(format)))
+	logger.Debug("exporting index")
+	var count uint
+	var err error
+	switch format {
+	case FormatNDJSON:
+		count, err = i.exportNDJSON(ctx, w)
+	case FormatCSV:
+		count, err = i.exportCSV(ctx, w)
+	case FormatTar, FormatZip:
+		count, err = i.exportArchive(ctx, w, format, logger)
+	default:
+		return 0, fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return count, err
+	}
+	logger.Debug("exported index", slog.Uint64("posts", uint64(count)))
+	return count, nil
+}
+
+// eachPost calls fn with every indexed post, ordered by number, and its stored image content if
+// withContent is true and it is stored.
+func (i *Index) eachPost(ctx context.Context, withContent bool, fn func(post *xkcd.Post, content []byte) error) error {
+	columns := postColumns
+	if !withContent {
+		columns = strings.Replace(columns, "content,", "NULL,", 1)
+	}
+	rows, err := i.db.QueryContext(ctx, "SELECT "+columns+" FROM posts ORDER BY num")
+	if err != nil {
+		return fmt.Errorf("failed to read posts: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		post, content, err := i.scanPostContent(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(post, content); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read posts: %w", err)
+	}
+	return nil
+}
+
+func (i *Index) exportNDJSON(ctx context.Context, w io.Writer) (uint, error) {
+	count := uint(0)
+	enc := json.NewEncoder(w)
+	err := i.eachPost(ctx, false, func(post *xkcd.Post, _ []byte) error {
+		if err := enc.Encode(post); err != nil {
+			return fmt.Errorf("failed to write post %d: %w", post.Num, err)
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+func (i *Index) exportCSV(ctx context.Context, w io.Writer) (uint, error) {
+	count := uint(0)
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvColumns); err != nil {
+		return 0, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	err := i.eachPost(ctx, false, func(post *xkcd.Post, _ []byte) error {
+		err := cw.Write([]string{
+			strconv.FormatUint(uint64(post.Num), 10),
+			post.Title,
+			post.Date.Format(time.DateOnly),
+			post.Link,
+			post.Img,
+			post.Alt,
+			post.Transcript,
+			post.News,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write post %d: %w", post.Num, err)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return count, fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return count, nil
+}
+
+// archiveWriter writes files to an archive.
+type archiveWriter interface {
+	// create adds a file to the archive, and returns a writer for its content of size bytes.
+	create(name string, size int64) (io.Writer, error)
+	Close() error
+}
+
+type tarArchiveWriter struct {
+	*tar.Writer
+}
+
+func (t tarArchiveWriter) create(name string, size int64) (io.Writer, error) {
+	err := t.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0o644,
+		ModTime:  time.Now(),
+	})
+	return t.Writer, err
+}
+
+type zipArchiveWriter struct {
+	*zip.Writer
+}
+
+func (z zipArchiveWriter) create(name string, _ int64) (io.Writer, error) {
+	return z.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+}
+
+func (i *Index) exportArchive(ctx context.Context, w io.Writer, format Format, logger *slog.Logger) (uint, error) {
+	var archive archiveWriter = tarArchiveWriter{tar.NewWriter(w)}
+	if format == FormatZip {
+		archive = zipArchiveWriter{zip.NewWriter(w)}
+	}
+	// Posts come first, so that they can be imported before their images while reading the archive.
+	posts := &bytes.Buffer{}
+	count, err := i.exportNDJSON(ctx, posts)
+	if err != nil {
+		return 0, err
+	}
+	f, err := archive.create(archivePostsFile, int64(posts.Len()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to add posts to archive: %w", err)
+	}
+	if _, err = io.Copy(f, posts); err != nil {
+		return 0, fmt.Errorf("failed to add posts to archive: %w", err)
+	}
+	images := uint(0)
+	err = i.eachPost(ctx, true, func(post *xkcd.Post, content []byte) error {
+		if content == nil {
+			return nil
+		}
+		name := path.Join(archiveImagesDir, strconv.FormatUint(uint64(post.Num), 10)+imageExtension(content))
+		f, err := archive.create(name, int64(len(content)))
+		if err == nil {
+			_, err = f.Write(content)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to add image of post %d to archive: %w", post.Num, err)
+		}
+		images++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	if err := archive.Close(); err != nil {
+		return count, fmt.Errorf("failed to write archive: %w", err)
+	}
+	logger.Debug("exported images", slog.Uint64("images", uint64(images)))
+	return count, nil
+}
+
+// imageExtension returns the file extension matching image content.
+func imageExtension(content []byte) string {
+	switch http.DetectContentType(content) {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	}
+	return ".bin"
+}
@@ -0,0 +1,18 @@
+package xkcdindex
+
+import "database/sql"
+
+// DB exposes the index database to tests.
+func (i *Index) DB() *sql.DB {
+	return i.db
+}
+
+// TranslateQuery exposes the parsing of search queries and their translation into SQL to tests.
+func TranslateQuery(query string) (string, []any, string, error) {
+	node, err := parseQuery(query)
+	if err != nil {
+		return "", nil, "", err
+	}
+	condition, args, ranking := translateQuery(node)
+	return condition, args, ranking, nil
+}
@@ -0,0 +1,210 @@
+package xkcdindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+// FailureClass is the class of error which made a post fail to be indexed.
+type FailureClass string
+
+const (
+	// FailureNotFound is a post which does not exist in xkcd API.
+	FailureNotFound FailureClass = "not_found"
+	// FailureTimeout is a request which timed out.
+	FailureTimeout FailureClass = "timeout"
+	// FailureAPI is an error response, or an invalid post, returned by xkcd API.
+	FailureAPI FailureClass = "api"
+	// FailureImage is an error while getting a post image in offline mode.
+	FailureImage FailureClass = "image"
+	// FailureOther is any other error.
+	FailureOther FailureClass = "other"
+)
+
+const (
+	// failureRetryDelay is the delay before the first retry of a failed post, doubled after each failed attempt.
+	failureRetryDelay = time.Hour
+	// failureMaxRetryDelay is the maximum delay between two retries of a failed post.
+	failureMaxRetryDelay = 7 * 24 * time.Hour
+)
+
+// FailedPost is a post which failed to be indexed, and is retried by updates.
+type FailedPost struct {
+	// Num is the number of the post.
+	Num uint `json:"num"`
+	// Class is the class of the last error.
+	Class FailureClass `json:"class"`
+	// Error is the last error message.
+	Error string `json:"error"`
+	// Attempts is how many times the post failed to be indexed.
+	Attempts uint `json:"attempts"`
+	// FirstFailedAt is the date of the first failure.
+	FirstFailedAt time.Time `json:"first_failed_at"`
+	// LastFailedAt is the date of the last failure.
+	LastFailedAt time.Time `json:"last_failed_at"`
+	// NextRetryAt is the date from which the next update retries the post.
+	NextRetryAt time.Time `json:"next_retry_at"`
+}
+
+// failureClass returns the class of an error returned while indexing a post.
+func failureClass(err error) FailureClass {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, xkcd.ErrNoSuchPost):
+		return FailureNotFound
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return FailureTimeout
+	case errors.Is(err, xkcd.ErrAPIError):
+		return FailureAPI
+	}
+	return FailureOther
+}
+
+// updateFailure is a post which failed to be indexed by an update.
+type updateFailure struct {
+	num   uint
+	class FailureClass
+	err   error
+}
+
+// updateFailures collects the posts which failed to be indexed by an update, to record them once it is over.
+type updateFailures struct {
+	mu       sync.Mutex
+	failures []updateFailure
+}
+
+// add adds post num, which failed to be indexed with the given error.
+func (f *updateFailures) add(num uint, class FailureClass, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = append(f.failures, updateFailure{num: num, class: class, err: err})
+}
+
+// recordFailures records failures in their own transaction.
+func (i *Index) recordFailures(ctx context.Context, failures []updateFailure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	for _, f := range failures {
+		if err := recordFailure(ctx, tx, f.num, f.class, f.err); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// recordFailure records that post num failed to be indexed, and schedules its next retry.
+func recordFailure(ctx context.Context, tx Execer, num uint, class FailureClass, failure error) error {
+	now := time.Now().Unix()
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO failed_posts
+			(num, error_class, error, attempts, first_failed_at, last_failed_at, next_retry_at)
+		VALUES
+			(?1, ?2, ?3, 1, ?4, ?4, ?4 + ?5)
+		ON CONFLICT (num) DO UPDATE SET
+			error_class = excluded.error_class,
+			error = excluded.error,
+			attempts = failed_posts.attempts + 1,
+			last_failed_at = excluded.last_failed_at,
+			next_retry_at = excluded.last_failed_at + min(?5 << min(failed_posts.attempts, 16), ?6)`,
+		num,
+		string(class),
+		failure.Error(),
+		now,
+		int64(failureRetryDelay.Seconds()),
+		int64(failureMaxRetryDelay.Seconds()),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record failure: %w", err)
+	}
+	return nil
+}
+
+// clearFailure removes post num from failed posts.
+func clearFailure(ctx context.Context, tx Execer, num uint) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM failed_posts WHERE num = ?", num); err != nil {
+		return fmt.Errorf("failed to clear failure: %w", err)
+	}
+	return nil
+}
+
+// HasDueFailures returns whether some failed posts should be retried now.
+func (i *Index) HasDueFailures(ctx context.Context) (bool, error) {
+	if i.db == nil {
+		return false, errors.New("index is not initialized")
+	}
+	due, err := i.dueFailures(ctx)
+	if err != nil {
+		return false, err
+	}
+	return len(due) > 0, nil
+}
+
+// dueFailures returns the numbers of failed posts which should be retried now.
+func (i *Index) dueFailures(ctx context.Context) ([]uint, error) {
+	rows, err := i.db.QueryContext(ctx, "SELECT num FROM failed_posts WHERE next_retry_at <= ? ORDER BY num", time.Now().Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list failed posts: %w", err)
+	}
+	defer rows.Close()
+	var nums []uint
+	for rows.Next() {
+		var num uint
+		if err := rows.Scan(&num); err != nil {
+			return nil, fmt.Errorf("failed to list failed posts: %w", err)
+		}
+		nums = append(nums, num)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list failed posts: %w", err)
+	}
+	return nums, nil
+}
+
+// Failures returns the posts which failed to be indexed, ordered by number.
+func (i *Index) Failures(ctx context.Context) ([]FailedPost, error) {
+	if i.db == nil {
+		return nil, errors.New("index is not initialized")
+	}
+	rows, err := i.db.QueryContext(
+		ctx,
+		`SELECT num, error_class, error, attempts, first_failed_at, last_failed_at, next_retry_at
+		FROM failed_posts
+		ORDER BY num`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list failed posts: %w", err)
+	}
+	defer rows.Close()
+	var failures []FailedPost
+	for rows.Next() {
+		var f FailedPost
+		var firstFailed, lastFailed, nextRetry int64
+		if err := rows.Scan(&f.Num, &f.Class, &f.Error, &f.Attempts, &firstFailed, &lastFailed, &nextRetry); err != nil {
+			return nil, fmt.Errorf("failed to list failed posts: %w", err)
+		}
+		f.FirstFailedAt = time.Unix(firstFailed, 0)
+		f.LastFailedAt = time.Unix(lastFailed, 0)
+		f.NextRetryAt = time.Unix(nextRetry, 0)
+		failures = append(failures, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list failed posts: %w", err)
+	}
+	return failures, nil
+}
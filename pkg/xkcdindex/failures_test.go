@@ -0,0 +1,157 @@
+package xkcdindex_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+// getFailingTestClient returns a client serving the test API, failing to serve post 5 and the image of post 7.
+func getFailingTestClient(t testing.TB) *xkcd.Client {
+	t.Helper()
+	working := getTestHTTPClient(t)
+	return xkcd.New(xkcd.WithClient(&http.Client{
+		Transport: mockRoundTripper(func(r *http.Request) (*http.Response, error) {
+			switch r.URL.Path {
+			case "/5/info.0.json":
+				return &http.Response{
+					StatusCode: http.StatusInternalServerError,
+					Body:       io.NopCloser(strings.NewReader("Internal Server Error")),
+				}, nil
+			case "/comics/7.png":
+				return &http.Response{
+					StatusCode: http.StatusBadGateway,
+					Body:       io.NopCloser(strings.NewReader("Bad Gateway")),
+				}, nil
+			}
+			return working.Do(r)
+		}),
+	}))
+}
+
+func failedNums(failures []xkcdindex.FailedPost) []uint {
+	nums := make([]uint, 0, len(failures))
+	for _, f := range failures {
+		nums = append(nums, f.Num)
+	}
+	return nums
+}
+
+func TestIndex_Failures(t *testing.T) {
+	ctx := context.Background()
+	idx, err := xkcdindex.New(filepath.Join(t.TempDir(), "test.index"), getLogger())
+	require.NoError(t, err)
+	defer idx.Close()
+	require.NoError(t, idx.Init(ctx, false, true))
+
+	t.Run("failures are recorded", func(t *testing.T) {
+		require.NoError(t, idx.Update(ctx, getFailingTestClient(t), 1, testPostsCount, 4), "expected failures not to fail update")
+		failures, err := idx.Failures(ctx)
+		require.NoError(t, err, "expected no error")
+		require.Equal(t, []uint{5, 7}, failedNums(failures), "expected failed posts to be recorded")
+		assert.Equal(t, xkcdindex.FailureAPI, failures[0].Class, "expected API failure")
+		assert.Equal(t, xkcdindex.FailureImage, failures[1].Class, "expected image failure")
+		assert.Equal(t, uint(1), failures[0].Attempts, "expected a single attempt")
+		assert.Equal(t, time.Hour, failures[0].NextRetryAt.Sub(failures[0].LastFailedAt), "expected first retry in an hour")
+		_, last, err := idx.GetLastUpdate()
+		assert.NoError(t, err, "expected no error")
+		assert.Equal(t, uint(testPostsCount), last, "expected update to reach last post")
+	})
+
+	t.Run("failures are not retried before their delay", func(t *testing.T) {
+		require.NoError(t, idx.RetryFailures(ctx, getFailingTestClient(t), 4), "expected no error")
+		failures, err := idx.Failures(ctx)
+		require.NoError(t, err, "expected no error")
+		assert.Equal(t, uint(1), failures[0].Attempts, "expected post not to be retried")
+	})
+
+	t.Run("retry delay is doubled", func(t *testing.T) {
+		_, err := idx.DB().ExecContext(ctx, "UPDATE failed_posts SET next_retry_at = 0")
+		require.NoError(t, err)
+		require.NoError(t, idx.RetryFailures(ctx, getFailingTestClient(t), 4), "expected no error")
+		failures, err := idx.Failures(ctx)
+		require.NoError(t, err, "expected no error")
+		require.Len(t, failures, 2, "expected posts to fail again")
+		assert.Equal(t, uint(2), failures[0].Attempts, "expected a second attempt")
+		assert.Equal(t, 2*time.Hour, failures[0].NextRetryAt.Sub(failures[0].LastFailedAt), "expected next retry in two hours")
+	})
+
+	t.Run("failures are retried by updates", func(t *testing.T) {
+		_, err := idx.DB().ExecContext(ctx, "UPDATE failed_posts SET next_retry_at = 0")
+		require.NoError(t, err)
+		require.NoError(t, idx.Update(ctx, getTestClient(t), testPostsCount, testPostsCount, 4), "expected no error")
+		failures, err := idx.Failures(ctx)
+		require.NoError(t, err, "expected no error")
+		assert.Empty(t, failures, "expected failed posts to be indexed")
+		meta, err := idx.GetImageMetadata(ctx, 7)
+		assert.NoError(t, err, "expected no error")
+		assert.NotNil(t, meta, "expected image to be stored")
+	})
+
+	t.Run("known absences are skipped", func(t *testing.T) {
+		require.NoError(t, idx.Update(ctx, getTestClient(t), 400, 405, 4), "expected no error")
+		failures, err := idx.Failures(ctx)
+		require.NoError(t, err, "expected no error")
+		assert.Equal(t, []uint{400, 401, 402, 403, 405}, failedNums(failures), "expected comic #404 not to be fetched")
+		assert.Equal(t, xkcdindex.FailureNotFound, failures[0].Class, "expected not found failure")
+	})
+}
+
+func TestIndex_Failures_ImageTooLarge(t *testing.T) {
+	ctx := context.Background()
+	idx, err := xkcdindex.New(filepath.Join(t.TempDir(), "test.index"), getLogger())
+	require.NoError(t, err)
+	defer idx.Close()
+	require.NoError(t, idx.Init(ctx, false, true))
+
+	client := xkcd.New(xkcd.WithClient(getTestHTTPClient(t)), xkcd.WithMaxImageBytes(10))
+	require.NoError(t, idx.Update(ctx, client, 1, 3, 4), "expected oversized images not to fail update")
+	failures, err := idx.Failures(ctx)
+	require.NoError(t, err, "expected no error")
+	require.Equal(t, []uint{1, 2, 3}, failedNums(failures), "expected posts with oversized images to be recorded")
+	for _, f := range failures {
+		assert.Equal(t, xkcdindex.FailureImage, f.Class, "expected image failure")
+		assert.Contains(t, f.Error, xkcd.ErrImageTooLarge.Error(), "expected limit error to be recorded")
+	}
+}
+
+func TestIndex_Failures_RolledBackUpdate(t *testing.T) {
+	ctx := context.Background()
+	idx, err := xkcdindex.New(filepath.Join(t.TempDir(), "test.index"), getLogger())
+	require.NoError(t, err)
+	defer idx.Close()
+	require.NoError(t, idx.Init(ctx, false, false))
+	// Indexing post 6 fails the whole update, after post 5 failed to be fetched.
+	_, err = idx.DB().ExecContext(
+		ctx,
+		"CREATE TRIGGER fail_post BEFORE INSERT ON posts WHEN NEW.num = 6 BEGIN SELECT RAISE(ABORT, 'failing post'); END",
+	)
+	require.NoError(t, err)
+
+	assert.Error(t, idx.Update(ctx, getFailingTestClient(t), 1, 10, 1), "expected update to fail")
+	posts, _, err := idx.Query(ctx, xkcdindex.Filter{}, xkcdindex.OrderNumAsc, xkcdindex.Page{})
+	require.NoError(t, err, "expected no error")
+	assert.Empty(t, posts, "expected update to be rolled back")
+	failures, err := idx.Failures(ctx)
+	require.NoError(t, err, "expected no error")
+	assert.Equal(t, []uint{5}, failedNums(failures), "expected failure to be kept")
+
+	due, err := idx.HasDueFailures(ctx)
+	require.NoError(t, err, "expected no error")
+	assert.False(t, due, "expected failure not to be due before its retry delay")
+	_, err = idx.DB().ExecContext(ctx, "UPDATE failed_posts SET next_retry_at = 0")
+	require.NoError(t, err)
+	due, err = idx.HasDueFailures(ctx)
+	require.NoError(t, err, "expected no error")
+	assert.True(t, due, "expected failure to be due")
+}
@@ -0,0 +1,146 @@
+package xkcdindex
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"log/slog"
+	"net/http"
+
+	// JPEG image format support.
+	_ "image/jpeg"
+	// PNG image format support.
+	_ "image/png"
+	// GIF image format support.
+	_ "image/gif"
+)
+
+// ImageMetadata describes a post image stored in an offline index.
+type ImageMetadata struct {
+	// ContentType is the MIME type of the image.
+	ContentType string `json:"content_type"`
+	// Size is the size of the image in bytes.
+	Size int64 `json:"size"`
+	// Width is the width of the image in pixels, zero if the image could not be decoded.
+	Width int `json:"width"`
+	// Height is the height of the image in pixels, zero if the image could not be decoded.
+	Height int `json:"height"`
+	// SHA256 is the hex encoded SHA-256 sum of the image.
+	SHA256 string `json:"sha256"`
+}
+
+// newImageMetadata computes the metadata of image content.
+func newImageMetadata(data []byte) ImageMetadata {
+	sum := sha256.Sum256(data)
+	meta := ImageMetadata{
+		ContentType: http.DetectContentType(data),
+		Size:        int64(len(data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+	}
+	if cfg, format, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		meta.ContentType = "image/" + format
+		meta.Width = cfg.Width
+		meta.Height = cfg.Height
+	}
+	return meta
+}
+
+// nullableInt returns v, or nil to store a NULL if v is zero.
+func nullableInt(v int) any {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
+
+// GetImageMetadata returns the metadata of the image of post num stored in index,
+// or nil if the post is not indexed or its image is not stored.
+func (i *Index) GetImageMetadata(ctx context.Context, num uint) (*ImageMetadata, error) {
+	if i.db == nil {
+		return nil, nil
+	}
+	row := i.db.QueryRowContext(
+		ctx,
+		`SELECT content_type, content_size, content_width, content_height, content_sha256
+		FROM posts
+		WHERE num = ? AND content IS NOT NULL`,
+		num,
+	)
+	var contentType, sum sql.NullString
+	var size, width, height sql.NullInt64
+	if err := row.Scan(&contentType, &size, &width, &height, &sum); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get image metadata: %w", err)
+	}
+	return &ImageMetadata{
+		ContentType: contentType.String,
+		Size:        size.Int64,
+		Width:       int(width.Int64),
+		Height:      int(height.Int64),
+		SHA256:      sum.String,
+	}, nil
+}
+
+// BackfillImageMetadata computes and stores the metadata of stored images that do not have it,
+// and returns how many images were updated.
+func (i *Index) BackfillImageMetadata(ctx context.Context) (uint, error) {
+	if i.db == nil {
+		return 0, errors.New("index is not initialized")
+	}
+	rows, err := i.db.QueryContext(ctx, "SELECT num FROM posts WHERE content IS NOT NULL AND content_sha256 IS NULL")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list images without metadata: %w", err)
+	}
+	var nums []uint
+	for rows.Next() {
+		var num uint
+		if err := rows.Scan(&num); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to list images without metadata: %w", err)
+		}
+		nums = append(nums, num)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list images without metadata: %w", err)
+	}
+
+	updated := uint(0)
+	for _, num := range nums {
+		log := i.logger.With(slog.Uint64("num", uint64(num)))
+		var data []byte
+		if err := i.db.QueryRowContext(ctx, "SELECT content FROM posts WHERE num = ?", num).Scan(&data); err != nil {
+			return updated, fmt.Errorf("failed to read image of post %d: %w", num, err)
+		}
+		meta := newImageMetadata(data)
+		_, err := i.db.ExecContext(
+			ctx,
+			`UPDATE posts SET
+				content_type = ?,
+				content_size = ?,
+				content_width = ?,
+				content_height = ?,
+				content_sha256 = ?
+			WHERE num = ?`,
+			meta.ContentType,
+			meta.Size,
+			nullableInt(meta.Width),
+			nullableInt(meta.Height),
+			meta.SHA256,
+			num,
+		)
+		if err != nil {
+			return updated, fmt.Errorf("failed to store image metadata of post %d: %w", num, err)
+		}
+		log.Debug("stored image metadata", slog.String("content_type", meta.ContentType))
+		updated++
+	}
+	return updated, nil
+}
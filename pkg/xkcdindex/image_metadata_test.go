@@ -0,0 +1,84 @@
+package xkcdindex_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+func TestIndex_GetImageMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("offline index", func(t *testing.T) {
+		idx := getTestIndexWithMode(t, true)
+		meta, err := idx.GetImageMetadata(ctx, 1)
+		require.NoError(t, err, "expected no error")
+		require.NotNil(t, meta, "expected metadata")
+		assert.Equal(t, "image/png", meta.ContentType, "expected PNG content type")
+		assert.Equal(t, int64(54845), meta.Size, "expected size to be stored")
+		assert.Equal(t, 281, meta.Width, "expected width to be stored")
+		assert.NotZero(t, meta.Height, "expected height to be stored")
+		assert.Len(t, meta.SHA256, 64, "expected SHA-256 to be stored")
+
+		meta, err = idx.GetImageMetadata(ctx, 2)
+		require.NoError(t, err, "expected no error")
+		require.NotNil(t, meta, "expected metadata")
+		assert.Equal(t, "image/jpeg", meta.ContentType, "expected JPEG content type")
+		assert.Equal(t, 577, meta.Width, "expected width to be stored")
+	})
+
+	t.Run("online index", func(t *testing.T) {
+		idx := getTestIndex(t)
+		meta, err := idx.GetImageMetadata(ctx, 1)
+		assert.NoError(t, err, "expected no error")
+		assert.Nil(t, meta, "expected no metadata without stored image")
+	})
+
+	t.Run("stored images are served with their content type", func(t *testing.T) {
+		idx := getTestIndexWithMode(t, true)
+		posts, _, err := idx.Query(ctx, xkcdindex.Filter{MaxNum: 2}, xkcdindex.OrderNumAsc, xkcdindex.Page{})
+		require.NoError(t, err, "expected no error")
+		require.Len(t, posts, 2, "expected posts")
+		for k, format := range []string{"png", "jpeg"} {
+			img, imgFormat, err := posts[k].GetImage(ctx)
+			require.NoError(t, err, "expected no error while getting image")
+			assert.Equal(t, format, imgFormat, "expected image to be decoded")
+			assert.NotZero(t, img.Bounds().Dx(), "expected image to be decoded")
+		}
+	})
+}
+
+func TestIndex_BackfillImageMetadata(t *testing.T) {
+	ctx := context.Background()
+	idx := getTestIndexWithMode(t, true)
+	expected, err := idx.GetImageMetadata(ctx, 3)
+	require.NoError(t, err)
+
+	updated, err := idx.BackfillImageMetadata(ctx)
+	require.NoError(t, err, "expected no error")
+	assert.Zero(t, updated, "expected no image to backfill on a new index")
+
+	clearImageMetadata(t, idx)
+	meta, err := idx.GetImageMetadata(ctx, 3)
+	require.NoError(t, err)
+	assert.Empty(t, meta.ContentType, "expected metadata to be cleared")
+
+	updated, err = idx.BackfillImageMetadata(ctx)
+	require.NoError(t, err, "expected no error")
+	assert.Equal(t, uint(testPostsCount), updated, "expected all images to be backfilled")
+	meta, err = idx.GetImageMetadata(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, expected, meta, "expected backfilled metadata to match the stored one")
+}
+
+// clearImageMetadata clears stored images metadata, as in indexes created before it was recorded.
+func clearImageMetadata(t *testing.T, idx *xkcdindex.Index) {
+	t.Helper()
+	_, err := idx.DB().Exec(`UPDATE posts SET
+		content_type = NULL, content_size = NULL, content_width = NULL, content_height = NULL, content_sha256 = NULL`)
+	require.NoError(t, err)
+}
@@ -0,0 +1,312 @@
+package xkcdindex
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+// maxRecordSize is the maximum size of a NDJSON record.
+const maxRecordSize = 16 * 1024 * 1024
+
+// Import reads posts from r in the given format, inserts or updates them in index, and returns how many
+// posts were imported. Posts are validated as posts retrieved from the API, and the import is aborted
+// without changing the index if one of them is invalid.
+// Images in archives are imported only if the index is in offline mode.
+func (i *Index) Import(ctx context.Context, r io.Reader, format Format) (uint, error) {
+	if i.db == nil {
+		return 0, errors.New("index is not initialized")
+	}
+	logger := i.logger.With(slog.String("format", string(format)))
+	logger.Debug("importing index")
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if errRollback := tx.Rollback(); errRollback != nil && !errors.Is(errRollback, sql.ErrTxDone) {
+			logger.Warn("failed to rollback transaction", slog.Any("error", errRollback))
+		}
+	}()
+
+	var count uint
+	switch format {
+	case FormatNDJSON:
+		count, err = importNDJSON(ctx, r, tx, logger)
+	case FormatCSV:
+		count, err = importCSV(ctx, r, tx, logger)
+	case FormatTar:
+		count, err = i.importTar(ctx, r, tx, logger)
+	case FormatZip:
+		count, err = i.importZip(ctx, r, tx, logger)
+	default:
+		return 0, fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return 0, err
+	}
+	// Imported posts do not need to be fetched by the next update, as long as they follow the last indexed post
+	// without gaps: posts missing after a gap are fetched by the next update.
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE last_update SET last_num = (
+			WITH RECURSIVE contiguous(num) AS (
+				SELECT last_num FROM last_update
+				UNION ALL
+				SELECT contiguous.num + 1 FROM contiguous
+				WHERE EXISTS (SELECT 1 FROM posts WHERE posts.num = contiguous.num + 1)
+					OR EXISTS (SELECT 1 FROM known_absences WHERE known_absences.num = contiguous.num + 1)
+			)
+			SELECT max(num) FROM contiguous
+		)`,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update last_update: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	logger.Debug("imported index", slog.Uint64("posts", uint64(count)))
+	return count, nil
+}
+
+func importNDJSON(ctx context.Context, r io.Reader, tx Execer, logger *slog.Logger) (uint, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+	count := uint(0)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var post xkcd.Post
+		if err := json.Unmarshal(scanner.Bytes(), &post); err != nil {
+			return 0, fmt.Errorf("invalid record on line %d: %w", line, err)
+		}
+		if err := importPost(ctx, &post, tx, logger); err != nil {
+			return 0, fmt.Errorf("invalid record on line %d: %w", line, err)
+		}
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read records: %w", err)
+	}
+	return count, nil
+}
+
+func importCSV(ctx context.Context, r io.Reader, tx Execer, logger *slog.Logger) (uint, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := map[string]int{}
+	for k, name := range header {
+		columns[strings.TrimSpace(name)] = k
+	}
+	for _, name := range csvColumns {
+		if _, ok := columns[name]; !ok {
+			return 0, fmt.Errorf("CSV header is missing column %q", name)
+		}
+	}
+	count := uint(0)
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		post, err := csvPost(record, columns)
+		if err == nil {
+			err = importPost(ctx, post, tx, logger)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("invalid record on line %d: %w", line, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+func csvPost(record []string, columns map[string]int) (*xkcd.Post, error) {
+	num, err := strconv.ParseUint(record[columns["num"]], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid post number: %w", err)
+	}
+	date, err := time.ParseInLocation(time.DateOnly, record[columns["date"]], time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date: %w", err)
+	}
+	return &xkcd.Post{
+		Alt:        record[columns["alt_text"]],
+		Day:        strconv.Itoa(date.Day()),
+		Img:        record[columns["image"]],
+		Link:       record[columns["link"]],
+		Month:      strconv.Itoa(int(date.Month())),
+		News:       record[columns["news"]],
+		Num:        uint(num),
+		Title:      record[columns["title"]],
+		Transcript: record[columns["transcript"]],
+		Year:       strconv.Itoa(date.Year()),
+	}, nil
+}
+
+// importPost validates an imported post and inserts or updates it in index, keeping its stored image if any.
+func importPost(ctx context.Context, post *xkcd.Post, tx Execer, logger *slog.Logger) error {
+	if err := post.Validate(); err != nil {
+		return err
+	}
+	return upsertPost(ctx, post, nil, tx, logger.With(slog.Uint64("num", uint64(post.Num))))
+}
+
+// importArchiveFile imports a file read from an archive, either the posts file or an image.
+// The posts file must be imported before images.
+func (i *Index) importArchiveFile(
+	ctx context.Context,
+	name string,
+	r io.Reader,
+	tx Execer,
+	postsRead bool,
+	logger *slog.Logger,
+) (uint, error) {
+	name = path.Clean(name)
+	if name == archivePostsFile {
+		return importNDJSON(ctx, r, tx, logger)
+	}
+	if path.Dir(name) != archiveImagesDir {
+		logger.Debug("ignoring unknown archive file", slog.String("file", name))
+		return 0, nil
+	}
+	if !postsRead {
+		return 0, fmt.Errorf("archive file %s must come before images", archivePostsFile)
+	}
+	if !i.offline {
+		return 0, nil
+	}
+	base := path.Base(name)
+	num, err := strconv.ParseUint(strings.TrimSuffix(base, path.Ext(base)), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid image file name %s, expected a post number", name)
+	}
+	data, err := i.readImage(name, r)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read image %s: %w", name, err)
+	}
+	if err := storeImageContent(ctx, tx, uint(num), data); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+func (i *Index) importTar(ctx context.Context, r io.Reader, tx Execer, logger *slog.Logger) (uint, error) {
+	tr := tar.NewReader(r)
+	count := uint(0)
+	postsRead := false
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		n, err := i.importArchiveFile(ctx, hdr.Name, tr, tx, postsRead, logger)
+		if err != nil {
+			return 0, err
+		}
+		postsRead = postsRead || path.Clean(hdr.Name) == archivePostsFile
+		count += n
+	}
+	if !postsRead {
+		return 0, fmt.Errorf("archive does not have a %s file", archivePostsFile)
+	}
+	return count, nil
+}
+
+func (i *Index) importZip(ctx context.Context, r io.Reader, tx Execer, logger *slog.Logger) (uint, error) {
+	// Zip archives are read from their end, they are first copied to a temporary file if r is not a regular
+	// file, such as a pipe.
+	f, ok := r.(*os.File)
+	if ok {
+		info, err := f.Stat()
+		ok = err == nil && info.Mode().IsRegular()
+	}
+	if !ok {
+		tmp, err := os.CreateTemp("", "xkcd-import-*.zip")
+		if err != nil {
+			return 0, fmt.Errorf("failed to create temporary file: %w", err)
+		}
+		defer func() {
+			_ = tmp.Close()
+			if err := os.Remove(tmp.Name()); err != nil {
+				logger.Warn("failed to remove temporary file", slog.Any("error", err))
+			}
+		}()
+		if _, err := io.Copy(tmp, r); err != nil {
+			return 0, fmt.Errorf("failed to read archive: %w", err)
+		}
+		f = tmp
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read archive: %w", err)
+	}
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return 0, fmt.Errorf("failed to read archive: %w", err)
+	}
+	files := make([]*zip.File, 0, len(zr.File))
+	var posts *zip.File
+	for _, file := range zr.File {
+		if path.Clean(file.Name) == archivePostsFile {
+			posts = file
+			continue
+		}
+		if !file.FileInfo().IsDir() {
+			files = append(files, file)
+		}
+	}
+	if posts == nil {
+		return 0, fmt.Errorf("archive does not have a %s file", archivePostsFile)
+	}
+	count := uint(0)
+	for k, file := range append([]*zip.File{posts}, files...) {
+		n, err := i.importZipFile(ctx, file, tx, k > 0, logger)
+		if err != nil {
+			return 0, err
+		}
+		count += n
+	}
+	return count, nil
+}
+
+func (i *Index) importZipFile(ctx context.Context, file *zip.File, tx Execer, postsRead bool, logger *slog.Logger) (uint, error) {
+	rdr, err := file.Open()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read archive file %s: %w", file.Name, err)
+	}
+	defer rdr.Close()
+	return i.importArchiveFile(ctx, file.Name, rdr, tx, postsRead, logger)
+}
@@ -0,0 +1,213 @@
+package xkcdindex_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+func allPosts(t testing.TB, idx *xkcdindex.Index) []xkcd.Post {
+	t.Helper()
+	posts, _, err := idx.Query(context.Background(), xkcdindex.Filter{}, xkcdindex.OrderNumAsc, xkcdindex.Page{})
+	require.NoError(t, err)
+	res := make([]xkcd.Post, 0, len(posts))
+	for _, post := range posts {
+		res = append(res, xkcd.Post{
+			Alt:        post.Alt,
+			Date:       post.Date,
+			Img:        post.Img,
+			Link:       post.Link,
+			News:       post.News,
+			Num:        post.Num,
+			Title:      post.Title,
+			Transcript: post.Transcript,
+		})
+	}
+	return res
+}
+
+func TestIndex_ExportImport(t *testing.T) {
+	ctx := context.Background()
+	src := getTestIndexWithMode(t, true)
+	expected := allPosts(t, src)
+	require.Len(t, expected, testPostsCount)
+
+	for _, format := range []xkcdindex.Format{
+		xkcdindex.FormatNDJSON,
+		xkcdindex.FormatCSV,
+		xkcdindex.FormatTar,
+		xkcdindex.FormatZip,
+	} {
+		t.Run(string(format), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			count, err := src.Export(ctx, buf, format)
+			require.NoError(t, err, "expected no error")
+			assert.Equal(t, uint(testPostsCount), count, "expected all posts to be exported")
+
+			dst := getEmptyIndex(t, true)
+			count, err = dst.Import(ctx, buf, format)
+			require.NoError(t, err, "expected no error")
+			assert.Equal(t, uint(testPostsCount), count, "expected all posts to be imported")
+			assert.Equal(t, expected, allPosts(t, dst), "expected imported posts to match exported ones")
+			_, last, err := dst.GetLastUpdate()
+			assert.NoError(t, err, "expected no error")
+			assert.Equal(t, uint(testPostsCount), last, "expected last post number to be updated")
+
+			meta, err := dst.GetImageMetadata(ctx, 2)
+			assert.NoError(t, err, "expected no error")
+			if format == xkcdindex.FormatTar || format == xkcdindex.FormatZip {
+				srcMeta, err := src.GetImageMetadata(ctx, 2)
+				require.NoError(t, err)
+				assert.Equal(t, srcMeta, meta, "expected image to be imported")
+			} else {
+				assert.Nil(t, meta, "expected no image to be imported")
+			}
+		})
+	}
+
+	t.Run("archive in online index", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		_, err := src.Export(ctx, buf, xkcdindex.FormatTar)
+		require.NoError(t, err)
+		dst := getEmptyIndex(t, false)
+		count, err := dst.Import(ctx, buf, xkcdindex.FormatTar)
+		require.NoError(t, err, "expected no error")
+		assert.Equal(t, uint(testPostsCount), count, "expected all posts to be imported")
+		meta, err := dst.GetImageMetadata(ctx, 2)
+		assert.NoError(t, err, "expected no error")
+		assert.Nil(t, meta, "expected images not to be imported")
+	})
+
+	t.Run("zip archive from a pipe", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		_, err := src.Export(ctx, buf, xkcdindex.FormatZip)
+		require.NoError(t, err)
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		defer r.Close()
+		go func() {
+			_, _ = io.Copy(w, buf)
+			_ = w.Close()
+		}()
+		dst := getEmptyIndex(t, true)
+		count, err := dst.Import(ctx, r, xkcdindex.FormatZip)
+		require.NoError(t, err, "expected no error")
+		assert.Equal(t, uint(testPostsCount), count, "expected all posts to be imported")
+	})
+
+	t.Run("archive images are limited", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		_, err := src.Export(ctx, buf, xkcdindex.FormatTar)
+		require.NoError(t, err)
+		dst, err := xkcdindex.New(filepath.Join(t.TempDir(), "limited.index"), getLogger(), xkcdindex.WithMaxImageBytes(16))
+		require.NoError(t, err)
+		defer dst.Close()
+		require.NoError(t, dst.Init(ctx, false, true))
+		count, err := dst.Import(ctx, buf, xkcdindex.FormatTar)
+		assert.ErrorIs(t, err, xkcd.ErrImageTooLarge, "expected images larger than the limit to fail")
+		assert.Zero(t, count, "expected no post to be imported")
+		assert.Empty(t, allPosts(t, dst), "expected index not to be changed")
+	})
+
+	t.Run("keeps stored images", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		_, err := src.Export(ctx, buf, xkcdindex.FormatNDJSON)
+		require.NoError(t, err)
+		_, err = src.Import(ctx, buf, xkcdindex.FormatNDJSON)
+		require.NoError(t, err, "expected no error")
+		meta, err := src.GetImageMetadata(ctx, 2)
+		assert.NoError(t, err, "expected no error")
+		assert.NotNil(t, meta, "expected stored image to be kept")
+	})
+}
+
+func TestIndex_Import(t *testing.T) {
+	ctx := context.Background()
+	valid := `{"num": 1, "title": "Barrel", "img": "https://imgs.xkcd.com/comics/barrel.jpg", "day": "1", "month": "1", "year": "2006"}`
+
+	tests := []struct {
+		name   string
+		format xkcdindex.Format
+		input  string
+		err    string
+	}{
+		{
+			name:   "invalid JSON",
+			format: xkcdindex.FormatNDJSON,
+			input:  valid + "\n{\"num\": 2,",
+			err:    "invalid record on line 2",
+		},
+		{
+			name:   "invalid post",
+			format: xkcdindex.FormatNDJSON,
+			input:  valid + "\n\n" + strings.Replace(valid, `"year": "2006"`, `"year": "foo"`, 1),
+			err:    "invalid record on line 3",
+		},
+		{
+			name:   "missing CSV column",
+			format: xkcdindex.FormatCSV,
+			input:  "num,title\n1,Barrel\n",
+			err:    `CSV header is missing column "date"`,
+		},
+		{
+			name:   "invalid CSV date",
+			format: xkcdindex.FormatCSV,
+			input:  "num,title,date,link,image,alt_text,transcript,news\n1,Barrel,yesterday,,https://imgs.xkcd.com/comics/barrel.jpg,,,\n",
+			err:    "invalid record on line 2: invalid date",
+		},
+		{
+			name:   "archive without posts",
+			format: xkcdindex.FormatTar,
+			input:  "",
+			err:    "archive does not have a posts.ndjson file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := getEmptyIndex(t, false)
+			count, err := idx.Import(ctx, strings.NewReader(tt.input), tt.format)
+			assert.ErrorContains(t, err, tt.err, "expected an error")
+			assert.Zero(t, count, "expected no post to be imported")
+			assert.Empty(t, allPosts(t, idx), "expected index not to be changed")
+		})
+	}
+}
+
+func TestIndex_Import_Sparse(t *testing.T) {
+	ctx := context.Background()
+	idx := getEmptyIndex(t, false)
+	records := func(nums ...int) string {
+		var b strings.Builder
+		for _, num := range nums {
+			b.WriteString(testPostJSON(num) + "\n")
+		}
+		return b.String()
+	}
+	lastNum := func() uint {
+		t.Helper()
+		_, num, err := idx.GetLastUpdate()
+		require.NoError(t, err, "expected no error")
+		return num
+	}
+
+	_, err := idx.Import(ctx, strings.NewReader(records(1, 2, 5, 6)), xkcdindex.FormatNDJSON)
+	require.NoError(t, err, "expected no error")
+	assert.Equal(t, uint(2), lastNum(), "expected last post number not to move past the gap")
+	stats, err := idx.Stats(ctx)
+	require.NoError(t, err, "expected no error")
+	assert.Equal(t, []uint{3, 4}, stats.Gaps, "expected the gap to be reported")
+
+	_, err = idx.Import(ctx, strings.NewReader(records(3, 4)), xkcdindex.FormatNDJSON)
+	require.NoError(t, err, "expected no error")
+	assert.Equal(t, uint(6), lastNum(), "expected last post number to move once the gap is filled")
+}
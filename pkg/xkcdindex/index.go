@@ -1,4 +1,5 @@
-package cli
+// Package xkcdindex provides a local SQLite index of xkcd posts, to search them or read them offline.
+package xkcdindex
 
 import (
 	"context"
@@ -9,23 +10,43 @@ import (
 	"os"
 	"time"
 
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+
 	// SQLite driver.
 	_ "modernc.org/sqlite"
 )
 
 // Index is an index instance.
 type Index struct {
-	db      *sql.DB
-	logger  *slog.Logger
-	offline bool
-	path    string
+	apiClient     *xkcd.Client
+	autoMigrate   bool
+	db            *sql.DB
+	httpClient    xkcd.HTTPClient
+	imageVariant  xkcd.ImageVariant
+	logger        *slog.Logger
+	maxImageBytes int64
+	offline       bool
+	path          string
 }
 
-// NewIndex creates a new index instance.
-func NewIndex(path string, logger *slog.Logger) (*Index, error) {
+// New creates a new index instance for the index file at path.
+// If the file does not exist, the index must be initialized with Init before use.
+// If the index has pending schema migrations, they are applied unless disabled with WithAutoMigrate.
+func New(path string, logger *slog.Logger, opts ...Option) (*Index, error) {
 	idx := &Index{
-		path:   path,
-		logger: logger.With(slog.String("index_path", path)),
+		autoMigrate:   true,
+		maxImageBytes: DefaultMaxImageBytes,
+		path:          path,
+		logger:        logger.With(slog.String("index_path", path)),
+	}
+	for _, opt := range opts {
+		opt(idx)
+	}
+	if idx.httpClient == nil && idx.apiClient != nil {
+		idx.httpClient = idx.apiClient.HTTPClient()
+	}
+	if idx.httpClient == nil {
+		idx.httpClient = xkcd.NewHTTPClient(nil)
 	}
 	i, err := os.Stat(path)
 	if errors.Is(err, os.ErrNotExist) {
@@ -39,6 +60,15 @@ func NewIndex(path string, logger *slog.Logger) (*Index, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
 	}
+	if idx.autoMigrate {
+		applied, err := idx.Migrate(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to migrate index: %w", err)
+		}
+		if len(applied) > 0 {
+			idx.logger.Info("migrated index", slog.Uint64("version", uint64(applied[len(applied)-1].Version)))
+		}
+	}
 	row := idx.db.QueryRow("SELECT value FROM settings WHERE name='offline' LIMIT 1")
 	if row.Err() != nil {
 		return nil, row.Err()
@@ -48,12 +78,15 @@ func NewIndex(path string, logger *slog.Logger) (*Index, error) {
 		return nil, row.Err()
 	}
 	idx.offline = offlineVal == "1"
+	if idx.imageVariant, err = readImageVariant(idx.db); err != nil {
+		return nil, err
+	}
 	idx.logger = idx.logger.With(slog.Bool("offline", idx.offline))
 	return idx, err
 }
 
 // Initialized returns true if the index is initialized.
-func (i *Index) Initized() bool {
+func (i *Index) Initialized() bool {
 	return i.db != nil
 }
 
@@ -66,67 +99,30 @@ func (i *Index) Init(ctx context.Context, force, offline bool) error {
 		if err := i.db.Close(); err != nil {
 			i.logger.With(slog.String("error", err.Error())).Warn("failed to close previous SQLite database")
 		}
+		i.db = nil
 		if err := os.Remove(i.path); err != nil {
 			return fmt.Errorf("failed to remove previous SQLite database: %w", err)
 		}
 	}
 	i.offline = offline
+	i.imageVariant = xkcd.Variant1x
 	i.logger.Debug("creating a new SQLite database")
 	db, err := sql.Open("sqlite", i.path)
 	if err != nil {
 		return fmt.Errorf("failed to create a new SQLite database: %w", err)
 	}
-	_, err = db.ExecContext(
-		ctx,
-		`create table posts
-(
-    num        INTEGER NOT NULL
-        CONSTRAINT posts_pk
-            primary key,
-    title      TEXT    NOT NULL,
-    image      TEXT    NOT NULL,
-    link       TEXT    NOT NULL,
-    date       INTEGER NOT NULL,
-    alt_text   TEXT    NOT NULL,
-    transcript TEXT    NOT NULL,
-    news       TEXT    NOT NULL,
-	content    BLOB    null,
-
-    CONSTRAINT date_check
-        check (date > 0),
-    CONSTRAINT num_check
-        check (num > 0)
-)`,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create posts table: %w", err)
-	}
-	_, err = db.ExecContext(ctx, "CREATE INDEX posts_content_index ON posts(title, alt_text, transcript, news);")
-	if err != nil {
-		return fmt.Errorf("failed to create content index: %w", err)
-	}
-	_, err = db.ExecContext(ctx, "CREATE TABLE last_update (date INTEGER NOT NULL, last_num INTEGER NOT NULL)")
-	if err != nil {
-		return fmt.Errorf("failed to create last_update table: %w", err)
-	}
-	_, err = db.ExecContext(ctx, "CREATE TABLE settings (name TEXT NOT NULL, value TEXT NOT NULL)")
-	if err != nil {
-		return fmt.Errorf("failed to create settings table: %w", err)
-	}
-	offlineVal := "0"
-	if offline {
-		offlineVal = "1"
-	}
-	_, err = db.ExecContext(ctx, "INSERT INTO settings (name, value) VALUES ('offline', ?)", offlineVal)
-	if err != nil {
-		return fmt.Errorf("failed to set offline setting: %w", err)
+	i.db = db
+	if _, err = i.Migrate(ctx); err != nil {
+		i.db = nil
+		if errClose := db.Close(); errClose != nil {
+			i.logger.Warn("failed to close SQLite database", slog.Any("error", errClose))
+		}
+		return fmt.Errorf("failed to create tables: %w", err)
 	}
-	_, err = db.ExecContext(ctx, "INSERT INTO last_update (date, last_num) VALUES (0, 0)")
-	if err != nil {
-		return fmt.Errorf("failed to set last_update: %w", err)
+	if err = i.setOffline(ctx, offline); err != nil {
+		return err
 	}
 	i.logger.Debug("created all tables")
-	i.db = db
 	return nil
 }
 
@@ -0,0 +1,156 @@
+package xkcdindex
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+// Get gets a post.
+// If it exists in index, it returns it.
+// Else, it fetches it from xkcd API client and stores it in index.
+func (i *Index) Get(ctx context.Context, client *xkcd.Client, num uint) (*xkcd.Post, error) {
+	post, err := i.getFromIndex(ctx, num)
+	if err != nil {
+		return nil, err
+	}
+	if post != nil {
+		return post, nil
+	}
+	post, err = client.GetPost(ctx, num)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch post from API: %w", err)
+	}
+	if err = i.indexPost(ctx, post, i.db, i.logger); err != nil {
+		i.logger.Warn("failed to index post", slog.Any("error", err))
+	}
+	return post, nil
+}
+
+func (i *Index) getFromIndex(ctx context.Context, num uint) (*xkcd.Post, error) {
+	if i.db == nil {
+		return nil, nil
+	}
+	row := i.db.QueryRowContext(ctx, "SELECT "+postColumns+" FROM posts WHERE num = ?", num)
+	if row.Err() != nil {
+		return nil, fmt.Errorf("failed to search post in index: %w", row.Err())
+	}
+	post, err := i.scanPost(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			i.logger.Debug("post not found in index")
+			return nil, nil
+		}
+		return nil, err
+	}
+	i.logger.Debug("post found in index")
+	return post, nil
+}
+
+// postColumns are the posts table columns, in the order scanPost expects them.
+const postColumns = "num, title, image, link, date, alt_text, transcript, news, content, content_type"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanPost scans a row made of postColumns, followed by extra columns, into a post.
+func (i *Index) scanPost(row rowScanner, extra ...any) (*xkcd.Post, error) {
+	post, _, err := i.scanPostContent(row, extra...)
+	return post, err
+}
+
+// scanPostContent is scanPost, also returning the image content stored in index, or nil if it is not stored.
+func (i *Index) scanPostContent(row rowScanner, extra ...any) (*xkcd.Post, []byte, error) {
+	return i.scanPostContentWith(i.apiClient, i.httpClient, row, extra...)
+}
+
+// scanPostContentWith is scanPostContent, with the post bound to apiClient and its image, if not stored in
+// index, fetched with httpClient.
+func (i *Index) scanPostContentWith(
+	apiClient *xkcd.Client,
+	httpClient xkcd.HTTPClient,
+	row rowScanner,
+	extra ...any,
+) (*xkcd.Post, []byte, error) {
+	getter := &getter{
+		client: httpClient,
+		logger: i.logger,
+	}
+	post := xkcd.NewPostWithClient(
+		apiClient,
+		getter,
+		i.logger,
+	)
+
+	var ts int64
+	var data *[]byte
+	var contentType sql.NullString
+
+	dest := []any{
+		&post.Num,
+		&post.Title,
+		&post.Img,
+		&post.Link,
+		&ts,
+		&post.Alt,
+		&post.Transcript,
+		&post.News,
+		&data,
+		&contentType,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+	post.Date = time.Unix(ts, 0)
+	post.Day = strconv.Itoa(post.Date.Day())
+	post.Month = strconv.Itoa(int(post.Date.Month()))
+	post.Year = strconv.Itoa(post.Date.Year())
+	if data == nil {
+		return post, nil, nil
+	}
+	getter.data = *data
+	getter.contentType = contentType.String
+	return post, *data, nil
+}
+
+type getter struct {
+	client      xkcd.HTTPClient
+	contentType string
+	data        []byte
+	logger      *slog.Logger
+}
+
+// Do implements the xkcd.HTTPClient interface.
+func (g *getter) Do(r *http.Request) (*http.Response, error) {
+	if g.data == nil {
+		g.logger.Debug("image was indexed online, serving from HTTP")
+		return g.client.Do(r)
+	}
+	g.logger.Debug("image was indexed offline, serving from index")
+	contentType := g.contentType
+	if contentType == "" {
+		// Images stored before metadata was recorded have no content type until backfilled.
+		contentType = http.DetectContentType(g.data)
+	}
+	headers := http.Header{}
+	headers.Set("Content-Type", contentType)
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Body:          io.NopCloser(bytes.NewReader(g.data)),
+		Header:        headers,
+		ContentLength: int64(len(g.data)),
+	}, nil
+}
@@ -0,0 +1,278 @@
+package xkcdindex
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+// Filter restricts the posts returned by a query, its zero value matching all posts.
+type Filter struct {
+	// From, if not zero, restricts posts to those published on or after it.
+	From time.Time
+	// To, if not zero, restricts posts to those published before it.
+	To time.Time
+	// MinNum, if not zero, restricts posts to those numbered MinNum or higher.
+	MinNum uint
+	// MaxNum, if not zero, restricts posts to those numbered MaxNum or lower.
+	MaxNum uint
+	// Text, if not empty, restricts posts to those matching this search query, see Search for its syntax.
+	Text string
+	// HasTranscript, if not nil, restricts posts to those having, or not having, a transcript.
+	HasTranscript *bool
+	// HasOfflineImage, if not nil, restricts posts to those having, or not having, their image stored in index.
+	HasOfflineImage *bool
+}
+
+// Order is the order of posts returned by a query.
+type Order int
+
+const (
+	// OrderNumDesc orders posts by number, latest first.
+	OrderNumDesc Order = iota
+	// OrderNumAsc orders posts by number, oldest first.
+	OrderNumAsc
+	// OrderDateDesc orders posts by publication date, latest first.
+	OrderDateDesc
+	// OrderDateAsc orders posts by publication date, oldest first.
+	OrderDateAsc
+	// OrderRelevance orders posts by relevance to the filter text, most relevant first.
+	// If the filter has no text, posts are ordered as with OrderNumDesc.
+	OrderRelevance
+	// OrderRandom orders posts randomly. Its pages are selected by offset only, no cursor is returned.
+	OrderRandom
+)
+
+// Page selects which part of the matching posts a query returns.
+type Page struct {
+	// Limit is the maximum number of posts to return, zero meaning no limit.
+	Limit uint
+	// Offset is the number of matching posts to skip. It is ignored if Cursor is set.
+	Offset uint
+	// Cursor is the cursor returned by a previous query with the same filter and order,
+	// to get the posts following the ones it returned.
+	Cursor string
+}
+
+// cursor is the position of the last post returned by a query.
+type cursor struct {
+	Num    uint  `json:"n"`
+	Date   int64 `json:"d"`
+	Offset uint  `json:"o"`
+}
+
+func (c cursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(b, &c)
+	}
+	if err != nil {
+		return cursor{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return c, nil
+}
+
+// Query returns the indexed posts matching filter, in the given order, paginated with page.
+// If more posts may follow, it also returns a cursor to use in the next page to get them, else an empty string.
+// A *QueryError is returned if the filter text is not a valid search query.
+func (i *Index) Query(ctx context.Context, filter Filter, order Order, page Page) ([]*xkcd.Post, string, error) {
+	results, next, err := i.query(ctx, filter, order, page)
+	if err != nil {
+		return nil, "", err
+	}
+	posts := make([]*xkcd.Post, 0, len(results))
+	for _, result := range results {
+		posts = append(posts, result.Post)
+	}
+	return posts, next, nil
+}
+
+func (i *Index) query(ctx context.Context, filter Filter, order Order, page Page) ([]SearchResult, string, error) {
+	if i.db == nil {
+		return nil, "", nil
+	}
+	conditions, args, ranking, err := filterConditions(filter)
+	if err != nil {
+		return nil, "", err
+	}
+	if order == OrderRelevance && ranking == "" {
+		order = OrderNumDesc
+	}
+
+	var after cursor
+	offset := page.Offset
+	if page.Cursor != "" {
+		if after, err = decodeCursor(page.Cursor); err != nil {
+			return nil, "", err
+		}
+		offset = after.Offset
+	}
+
+	var orderBy string
+	switch order {
+	case OrderNumAsc:
+		orderBy = "posts.num ASC"
+		if page.Cursor != "" {
+			conditions = append(conditions, "posts.num > ?")
+			args = append(args, after.Num)
+		}
+	case OrderDateDesc:
+		orderBy = "posts.date DESC, posts.num DESC"
+		if page.Cursor != "" {
+			conditions = append(conditions, "(posts.date < ? OR (posts.date = ? AND posts.num < ?))")
+			args = append(args, after.Date, after.Date, after.Num)
+		}
+	case OrderDateAsc:
+		orderBy = "posts.date ASC, posts.num ASC"
+		if page.Cursor != "" {
+			conditions = append(conditions, "(posts.date > ? OR (posts.date = ? AND posts.num > ?))")
+			args = append(args, after.Date, after.Date, after.Num)
+		}
+	case OrderRelevance:
+		// Scores are not stable enough to seek on, relevance pages are selected by offset.
+		orderBy = "ranking.score DESC, posts.num DESC"
+	case OrderRandom:
+		orderBy = "random()"
+	default:
+		orderBy = "posts.num DESC"
+		if page.Cursor != "" {
+			conditions = append(conditions, "posts.num < ?")
+			args = append(args, after.Num)
+		}
+	}
+	if order != OrderRelevance && order != OrderRandom && page.Cursor != "" {
+		offset = 0
+	}
+
+	// Posts are ranked on the non negated text terms of the query, if any.
+	selectRank := "0.0, ''"
+	from := "posts"
+	if ranking != "" {
+		selectRank = "coalesce(ranking.score, 0.0), coalesce(ranking.snippet, '')"
+		from = `posts LEFT JOIN (
+			SELECT rowid, -bm25(posts_fts, 10.0, 5.0, 1.0, 1.0) AS score, snippet(posts_fts, -1, ?, ?, '…', 16) AS snippet
+			FROM posts_fts
+			WHERE posts_fts MATCH ?
+		) AS ranking ON ranking.rowid = posts.num`
+		args = append([]any{HighlightStart, HighlightEnd, ranking}, args...)
+	}
+	where := "1"
+	if len(conditions) > 0 {
+		where = strings.Join(conditions, " AND ")
+	}
+	limit := int64(-1)
+	if page.Limit > 0 {
+		limit = int64(page.Limit)
+	}
+	args = append(args, limit, offset)
+
+	logger := i.logger.With(slog.String("where", where), slog.String("ranking", ranking))
+	logger.Debug("querying index")
+	rows, err := i.db.QueryContext(
+		ctx,
+		`SELECT `+prefixColumns("posts.", postColumns)+`, `+selectRank+`
+		FROM `+from+`
+		WHERE `+where+`
+		ORDER BY `+orderBy+`
+		LIMIT ? OFFSET ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query index: %w", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var result SearchResult
+		if result.Post, err = i.scanPost(rows, &result.Score, &result.Snippet); err != nil {
+			return nil, "", err
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to query index: %w", err)
+	}
+	logger.Debug("queried index", slog.Int("results", len(results)))
+
+	if page.Limit == 0 || uint(len(results)) < page.Limit || order == OrderRandom {
+		return results, "", nil
+	}
+	last := results[len(results)-1].Post
+	next := cursor{
+		Num:    last.Num,
+		Date:   last.Date.Unix(),
+		Offset: offset + uint(len(results)),
+	}
+	return results, next.encode(), nil
+}
+
+// filterConditions returns the SQL conditions on the posts table selecting posts matching filter, their
+// arguments, and the FTS5 query ranking posts on the filter text, if any.
+func filterConditions(filter Filter) ([]string, []any, string, error) {
+	var conditions []string
+	var args []any
+	ranking := ""
+	if strings.TrimSpace(filter.Text) != "" {
+		node, err := parseQuery(filter.Text)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		var where string
+		var whereArgs []any
+		where, whereArgs, ranking = translateQuery(node)
+		conditions = append(conditions, where)
+		args = append(args, whereArgs...)
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "posts.date >= ?")
+		args = append(args, filter.From.Unix())
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "posts.date < ?")
+		args = append(args, filter.To.Unix())
+	}
+	if filter.MinNum > 0 {
+		conditions = append(conditions, "posts.num >= ?")
+		args = append(args, filter.MinNum)
+	}
+	if filter.MaxNum > 0 {
+		conditions = append(conditions, "posts.num <= ?")
+		args = append(args, filter.MaxNum)
+	}
+	if filter.HasTranscript != nil {
+		if *filter.HasTranscript {
+			conditions = append(conditions, "posts.transcript != ''")
+		} else {
+			conditions = append(conditions, "posts.transcript = ''")
+		}
+	}
+	if filter.HasOfflineImage != nil {
+		if *filter.HasOfflineImage {
+			conditions = append(conditions, "posts.content IS NOT NULL")
+		} else {
+			conditions = append(conditions, "posts.content IS NULL")
+		}
+	}
+	return conditions, args, ranking, nil
+}
+
+func prefixColumns(prefix, columns string) string {
+	parts := strings.Split(columns, ", ")
+	for k, column := range parts {
+		parts[k] = prefix + column
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,145 @@
+package xkcdindex_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+func TestIndex_Query(t *testing.T) {
+	ctx := context.Background()
+	idx := getTestIndex(t)
+	yes := true
+	no := false
+
+	t.Run("all posts", func(t *testing.T) {
+		posts, next, err := idx.Query(ctx, xkcdindex.Filter{}, xkcdindex.OrderNumAsc, xkcdindex.Page{})
+		require.NoError(t, err, "expected no error")
+		require.Len(t, posts, testPostsCount, "expected all posts")
+		assert.Empty(t, next, "expected no cursor without limit")
+		assert.Equal(t, uint(1), posts[0].Num, "expected posts to be ordered")
+		assert.Equal(t, "Petit Trees", posts[0].Title, "expected post to be read from index")
+		assert.Equal(t, time.Date(2006, time.January, 1, 0, 0, 0, 0, time.Local), posts[0].Date, "expected post date to be read from index")
+	})
+
+	cases := []struct {
+		name     string
+		filter   xkcdindex.Filter
+		order    xkcdindex.Order
+		expected []uint
+	}{
+		{
+			name:     "number range",
+			filter:   xkcdindex.Filter{MinNum: 5, MaxNum: 8},
+			order:    xkcdindex.OrderNumDesc,
+			expected: []uint{8, 7, 6, 5},
+		},
+		{
+			name: "date range",
+			filter: xkcdindex.Filter{
+				From: time.Date(2008, time.August, 1, 0, 0, 0, 0, time.Local),
+				To:   time.Date(2008, time.October, 1, 0, 0, 0, 0, time.Local),
+			},
+			order:    xkcdindex.OrderDateAsc,
+			expected: []uint{28, 29},
+		},
+		{
+			name:     "with transcript",
+			filter:   xkcdindex.Filter{HasTranscript: &yes, MaxNum: 10},
+			order:    xkcdindex.OrderNumAsc,
+			expected: []uint{1, 3, 5, 7, 9},
+		},
+		{
+			name:     "without transcript",
+			filter:   xkcdindex.Filter{HasTranscript: &no, MaxNum: 10},
+			order:    xkcdindex.OrderDateDesc,
+			expected: []uint{10, 8, 6, 4, 2},
+		},
+		{
+			name:     "with offline image",
+			filter:   xkcdindex.Filter{HasOfflineImage: &yes},
+			order:    xkcdindex.OrderNumAsc,
+			expected: []uint{},
+		},
+		{
+			name:     "text",
+			filter:   xkcdindex.Filter{Text: "island", MinNum: 10},
+			order:    xkcdindex.OrderNumAsc,
+			expected: []uint{12, 22},
+		},
+		{
+			name:     "relevance without text",
+			filter:   xkcdindex.Filter{MinNum: 28},
+			order:    xkcdindex.OrderRelevance,
+			expected: []uint{30, 29, 28},
+		},
+	}
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			posts, _, err := idx.Query(ctx, test.filter, test.order, xkcdindex.Page{})
+			require.NoError(t, err, "expected no error")
+			assert.Equal(t, test.expected, postNums(posts), "expected matching posts in order")
+		})
+	}
+
+	t.Run("offset", func(t *testing.T) {
+		posts, _, err := idx.Query(ctx, xkcdindex.Filter{}, xkcdindex.OrderNumAsc, xkcdindex.Page{Limit: 3, Offset: 10})
+		require.NoError(t, err, "expected no error")
+		assert.Equal(t, []uint{11, 12, 13}, postNums(posts), "expected offset to be applied")
+	})
+
+	for _, order := range []xkcdindex.Order{
+		xkcdindex.OrderNumDesc,
+		xkcdindex.OrderNumAsc,
+		xkcdindex.OrderDateDesc,
+		xkcdindex.OrderDateAsc,
+		xkcdindex.OrderRelevance,
+	} {
+		t.Run("cursor pagination", func(t *testing.T) {
+			filter := xkcdindex.Filter{}
+			if order == xkcdindex.OrderRelevance {
+				filter.Text = "barrel OR island"
+			}
+			all, _, err := idx.Query(ctx, filter, order, xkcdindex.Page{})
+			require.NoError(t, err, "expected no error")
+
+			var paginated []uint
+			page := xkcdindex.Page{Limit: 7}
+			for range len(all) {
+				posts, next, err := idx.Query(ctx, filter, order, page)
+				require.NoError(t, err, "expected no error")
+				paginated = append(paginated, postNums(posts)...)
+				if next == "" {
+					break
+				}
+				page.Cursor = next
+			}
+			assert.Equal(t, postNums(all), paginated, "expected pages to hold all posts in order")
+		})
+	}
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		posts, _, err := idx.Query(ctx, xkcdindex.Filter{}, xkcdindex.OrderNumAsc, xkcdindex.Page{Cursor: "not a cursor"})
+		assert.Nil(t, posts, "expected no posts")
+		assert.ErrorContains(t, err, "invalid cursor", "expected an invalid cursor error")
+	})
+
+	t.Run("invalid text", func(t *testing.T) {
+		posts, _, err := idx.Query(ctx, xkcdindex.Filter{Text: "title:"}, xkcdindex.OrderNumAsc, xkcdindex.Page{})
+		assert.Nil(t, posts, "expected no posts")
+		var queryErr *xkcdindex.QueryError
+		assert.ErrorAs(t, err, &queryErr, "expected a QueryError")
+	})
+
+	t.Run("posts images are available", func(t *testing.T) {
+		posts, _, err := idx.Query(ctx, xkcdindex.Filter{MinNum: 1, MaxNum: 1}, xkcdindex.OrderNumAsc, xkcdindex.Page{})
+		require.NoError(t, err, "expected no error")
+		require.Len(t, posts, 1, "expected a post")
+		assert.Equal(t, "https://imgs.xkcd.com/comics/1.png", posts[0].Img, "expected image URL to be read from index")
+	})
+}
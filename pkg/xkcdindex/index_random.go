@@ -0,0 +1,87 @@
+package xkcdindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+// Random returns an indexed post matching filter, picked uniformly.
+// It returns an error wrapping xkcd.ErrNoSuchPost if no indexed post matches filter, and a *QueryError if
+// the filter text is not a valid search query.
+func (i *Index) Random(ctx context.Context, filter Filter) (*xkcd.Post, error) {
+	if i.db == nil {
+		return nil, errors.New("index is not initialized")
+	}
+	posts, _, err := i.Query(ctx, filter, OrderRandom, Page{Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(posts) == 0 {
+		return nil, fmt.Errorf("%w: no indexed post matches filter", xkcd.ErrNoSuchPost)
+	}
+	return posts[0], nil
+}
+
+// DailyPick returns the indexed post picked by xkcd.DailyPick for date and seed, among indexed posts matching
+// filter and published on or before the calendar day of date, see xkcd.DailyPickEnd.
+// Picks are the same as xkcd.Client.GetDailyPick ones if all posts are indexed and filter is empty.
+// It returns an error wrapping xkcd.ErrNoSuchPost if no indexed post matches, and a *QueryError if the filter
+// text is not a valid search query.
+func (i *Index) DailyPick(ctx context.Context, seed string, date time.Time, filter Filter) (*xkcd.Post, error) {
+	if i.db == nil {
+		return nil, errors.New("index is not initialized")
+	}
+	end := xkcd.DailyPickEnd(date)
+	if filter.To.IsZero() || filter.To.After(end) {
+		filter.To = end
+	}
+	nums, err := i.queryNums(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(nums) == 0 {
+		return nil, fmt.Errorf("%w: no indexed post matches filter", xkcd.ErrNoSuchPost)
+	}
+	post, err := i.getFromIndex(ctx, xkcd.DailyPick(seed, date, nums))
+	if err != nil {
+		return nil, err
+	}
+	if post == nil {
+		return nil, fmt.Errorf("%w: picked post was removed from index", xkcd.ErrNoSuchPost)
+	}
+	return post, nil
+}
+
+// queryNums returns the numbers of the indexed posts matching filter, in ascending order, without loading posts.
+func (i *Index) queryNums(ctx context.Context, filter Filter) ([]uint, error) {
+	conditions, args, _, err := filterConditions(filter)
+	if err != nil {
+		return nil, err
+	}
+	where := "1"
+	if len(conditions) > 0 {
+		where = strings.Join(conditions, " AND ")
+	}
+	rows, err := i.db.QueryContext(ctx, "SELECT posts.num FROM posts WHERE "+where+" ORDER BY posts.num ASC", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query index: %w", err)
+	}
+	defer rows.Close()
+	var nums []uint
+	for rows.Next() {
+		var num uint
+		if err := rows.Scan(&num); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		nums = append(nums, num)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query index: %w", err)
+	}
+	return nums, nil
+}
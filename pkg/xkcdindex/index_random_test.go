@@ -0,0 +1,139 @@
+package xkcdindex_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+func TestIndex_Random(t *testing.T) {
+	ctx := context.Background()
+	idx := getTestIndex(t)
+	yes := true
+
+	t.Run("posts are picked among matching ones", func(t *testing.T) {
+		picked := map[uint]bool{}
+		for range 100 {
+			post, err := idx.Random(ctx, xkcdindex.Filter{MinNum: 5, MaxNum: 8})
+			require.NoError(t, err, "expected no error")
+			require.NotNil(t, post, "expected a post")
+			assert.True(t, post.Num >= 5 && post.Num <= 8, "expected post to match filter")
+			picked[post.Num] = true
+		}
+		assert.Len(t, picked, 4, "expected all matching posts to be picked")
+	})
+
+	t.Run("filters are combined", func(t *testing.T) {
+		post, err := idx.Random(ctx, xkcdindex.Filter{HasTranscript: &yes, MinNum: 2, MaxNum: 3})
+		require.NoError(t, err, "expected no error")
+		assert.Equal(t, uint(3), post.Num, "expected the only matching post")
+	})
+
+	t.Run("no matching post", func(t *testing.T) {
+		_, err := idx.Random(ctx, xkcdindex.Filter{MinNum: testPostsCount + 1})
+		assert.ErrorIs(t, err, xkcd.ErrNoSuchPost, "expected no such post error")
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		_, err := idx.Random(ctx, xkcdindex.Filter{Text: "title:("})
+		var queryErr *xkcdindex.QueryError
+		assert.ErrorAs(t, err, &queryErr, "expected a query error")
+	})
+}
+
+func TestIndex_DailyPick(t *testing.T) {
+	ctx := context.Background()
+	idx := getTestIndex(t)
+	posts, _, err := idx.Query(ctx, xkcdindex.Filter{}, xkcdindex.OrderDateAsc, xkcdindex.Page{})
+	require.NoError(t, err, "expected no error")
+	require.Len(t, posts, testPostsCount, "expected all posts")
+
+	t.Run("picks are the same as the client ones", func(t *testing.T) {
+		byNum, _, err := idx.Query(ctx, xkcdindex.Filter{}, xkcdindex.OrderNumAsc, xkcdindex.Page{})
+		require.NoError(t, err, "expected no error")
+		date := time.Now()
+		post, err := idx.DailyPick(ctx, "team", date, xkcdindex.Filter{})
+		require.NoError(t, err, "expected no error")
+		assert.Equal(t, xkcd.DailyPick("team", date, postNums(byNum)), post.Num, "expected the same pick")
+	})
+
+	t.Run("picks are the same as the client ones in any location", func(t *testing.T) {
+		client := getTestClient(t)
+		for _, loc := range []*time.Location{
+			time.Local,
+			time.UTC,
+			time.FixedZone("UTC+14", 14*60*60),
+			time.FixedZone("UTC-12", -12*60*60),
+		} {
+			for _, date := range []time.Time{
+				time.Date(2007, time.April, 30, 23, 0, 0, 0, loc),
+				time.Date(2007, time.May, 1, 1, 0, 0, 0, loc),
+			} {
+				for k := range 5 {
+					seed := fmt.Sprintf("team-%d", k)
+					expected, err := client.GetDailyPick(ctx, seed, date)
+					require.NoError(t, err, "expected no error")
+					post, err := idx.DailyPick(ctx, seed, date, xkcdindex.Filter{})
+					require.NoError(t, err, "expected no error")
+					assert.Equal(t, expected.Num, post.Num, "expected the same pick as the client on %s", date)
+				}
+			}
+		}
+	})
+
+	t.Run("posts newer than date are excluded", func(t *testing.T) {
+		date := posts[9].Date.Add(10 * time.Hour)
+		for k := range 20 {
+			post, err := idx.DailyPick(ctx, fmt.Sprintf("team-%d", k), date, xkcdindex.Filter{})
+			require.NoError(t, err, "expected no error")
+			assert.False(t, post.Date.After(posts[9].Date), "expected post not to be newer than date")
+		}
+	})
+
+	t.Run("filter is applied", func(t *testing.T) {
+		for k := range 20 {
+			post, err := idx.DailyPick(ctx, fmt.Sprintf("team-%d", k), time.Now(), xkcdindex.Filter{MinNum: 20, MaxNum: 22})
+			require.NoError(t, err, "expected no error")
+			assert.True(t, post.Num >= 20 && post.Num <= 22, "expected post to match filter")
+		}
+	})
+
+	t.Run("date before the first post", func(t *testing.T) {
+		_, err := idx.DailyPick(ctx, "team", posts[0].Date.AddDate(0, 0, -1), xkcdindex.Filter{})
+		assert.ErrorIs(t, err, xkcd.ErrNoSuchPost, "expected no such post error")
+	})
+
+	t.Run("text filter is applied", func(t *testing.T) {
+		matching, _, err := idx.Query(ctx, xkcdindex.Filter{Text: "title:" + testTitles[3]}, xkcdindex.OrderNumAsc, xkcdindex.Page{})
+		require.NoError(t, err, "expected no error")
+		require.NotEmpty(t, matching, "expected posts to match")
+		date := time.Now()
+		post, err := idx.DailyPick(ctx, "team", date, xkcdindex.Filter{Text: "title:" + testTitles[3]})
+		require.NoError(t, err, "expected no error")
+		assert.Equal(t, xkcd.DailyPick("team", date, postNums(matching)), post.Num, "expected pick among matching posts")
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		_, err := idx.DailyPick(ctx, "team", time.Now(), xkcdindex.Filter{Text: "title:"})
+		var queryErr *xkcdindex.QueryError
+		assert.ErrorAs(t, err, &queryErr, "expected a query error")
+	})
+
+	t.Run("offline index", func(t *testing.T) {
+		offline := getTestIndexWithMode(t, true)
+		date := time.Now()
+		post, err := offline.DailyPick(ctx, "team", date, xkcdindex.Filter{})
+		require.NoError(t, err, "expected no error")
+		assert.Equal(t, xkcd.DailyPick("team", date, postNums(posts)), post.Num, "expected the same pick")
+		rdr, err := post.GetImageContent(ctx)
+		require.NoError(t, err, "expected picked post image to be served from index")
+		assert.NoError(t, rdr.Close(), "expected no error while closing image")
+	})
+}
@@ -0,0 +1,45 @@
+package xkcdindex
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+const (
+	// HighlightStart marks the beginning of a matched term in a search result snippet.
+	HighlightStart = "<mark>"
+	// HighlightEnd marks the end of a matched term in a search result snippet.
+	HighlightEnd = "</mark>"
+)
+
+// SearchResult is a post matching a search.
+type SearchResult struct {
+	// Post is the matching post.
+	Post *xkcd.Post `json:"post"`
+	// Score is the relevance of the post for the search, higher is better.
+	Score float64 `json:"score"`
+	// Snippet is an excerpt of the post matching the search, with matched terms
+	// surrounded by HighlightStart and HighlightEnd.
+	Snippet string `json:"snippet"`
+}
+
+// Search returns at most limit indexed posts matching the given query, the most relevant first.
+//
+// A query is made of terms, implicitly combined with AND, and which can be combined with
+// OR, negated with NOT or a leading -, and grouped with parentheses. A term is a word,
+// a word prefix ending with *, or a quoted phrase, which can be restricted to a field with
+// title:, alt:, transcript: or news:.
+// Posts can also be filtered with num:, year: and date: (YYYY, YYYY-MM or YYYY-MM-DD) using a
+// single value, a range such as 2010..2012 (with optional bounds), or a comparison such
+// as >1500 or <=2010-05, and with before: and after: dates.
+//
+// A *QueryError is returned if the query is invalid.
+func (i *Index) Search(ctx context.Context, query string, limit uint) ([]SearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, &QueryError{Query: query, Message: "query is empty"}
+	}
+	results, _, err := i.query(ctx, Filter{Text: query}, OrderRelevance, Page{Limit: limit})
+	return results, err
+}
@@ -0,0 +1,264 @@
+package xkcdindex_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+func TestIndex_Search(t *testing.T) {
+	ctx := context.Background()
+	idx := getTestIndex(t)
+
+	t.Run("ranking and snippets", func(t *testing.T) {
+		results, err := idx.Search(ctx, "barrel", 100)
+		require.NoError(t, err, "expected no error")
+		require.Len(t, results, 18, "expected posts matching in title or transcript")
+		top := []*xkcd.Post{results[0].Post, results[1].Post, results[2].Post}
+		assert.ElementsMatch(t, []uint{10, 20, 30}, postNums(top), "expected title matches to rank first")
+		assert.Greater(t, results[0].Score, results[len(results)-1].Score, "expected results to be ranked")
+		assert.Contains(t, results[0].Snippet, xkcdindex.HighlightStart+"Barrel"+xkcdindex.HighlightEnd, "expected match to be highlighted")
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		results, err := idx.Search(ctx, "barrel", 5)
+		require.NoError(t, err, "expected no error")
+		assert.Len(t, results, 5, "expected limit to be applied")
+	})
+
+	cases := []struct {
+		query    string
+		expected []uint
+	}{
+		{query: "island", expected: []uint{2, 12, 22}},
+		{query: "ISLAND", expected: []uint{2, 12, 22}},
+		{query: "isl*", expected: []uint{2, 12, 22}},
+		{query: `"petit trees"`, expected: []uint{1, 11, 21}},
+		{query: `"trees petit"`, expected: nil},
+		{query: "title:barrel", expected: []uint{10, 20, 30}},
+		{query: "barrel -title:barrel num:<10", expected: []uint{1, 3, 5, 7, 9}},
+		{query: "barrel NOT transcript:barrel", expected: []uint{10, 20, 30}},
+		{query: "title:island OR title:irony", expected: []uint{2, 5, 12, 15, 22, 25}},
+		{query: "(title:island OR title:irony) AND year:2007", expected: []uint{12, 15}},
+		{query: "title:island year:2006..2007", expected: []uint{2, 12}},
+		{query: "transcript:wonder num:>25", expected: []uint{27, 29}},
+		{query: "num:>=28", expected: []uint{28, 29, 30}},
+		{query: "num:..2", expected: []uint{1, 2}},
+		{query: "num:14", expected: []uint{14}},
+		{query: "date:2007-02", expected: []uint{12}},
+		{query: "date:2007-02-01..2007-03", expected: []uint{12, 13}},
+		{query: "before:2006-03", expected: []uint{1, 2}},
+		{query: "after:2008-09", expected: []uint{30}},
+		{query: `alt:"post 7"`, expected: []uint{7}},
+	}
+	for _, test := range cases {
+		t.Run(fmt.Sprintf("query %s", test.query), func(t *testing.T) {
+			results, err := idx.Search(ctx, test.query, 100)
+			require.NoError(t, err, "expected no error")
+			posts := make([]*xkcd.Post, 0, len(results))
+			for _, result := range results {
+				posts = append(posts, result.Post)
+			}
+			assert.ElementsMatch(t, test.expected, postNums(posts), "expected matching posts")
+		})
+	}
+
+	errorCases := []struct {
+		query   string
+		pos     int
+		message string
+	}{
+		{query: "", pos: 0, message: "query is empty"},
+		{query: "island (title:barrel", pos: 7, message: "unclosed parenthesis"},
+		{query: "island)", pos: 6, message: "unexpected )"},
+		{query: "author:randall", pos: 0, message: `unknown field "author"`},
+		{query: "barrel year:", pos: 12, message: `missing value for field "year"`},
+		{query: `alt:"unterminated`, pos: 4, message: "unterminated quoted string"},
+		{query: "island OR", pos: 9, message: "unexpected end of query"},
+		{query: "num:20..10", pos: 0, message: `range of field "num" is empty`},
+		{query: "num:=>10", pos: 0, message: `invalid comparison operator "=>"`},
+		{query: "year:twenty", pos: 0, message: `invalid year "twenty"`},
+		{query: "x before:2010-13", pos: 2, message: `invalid date "2010-13"`},
+	}
+	for _, test := range errorCases {
+		t.Run(fmt.Sprintf("invalid query %s", test.query), func(t *testing.T) {
+			results, err := idx.Search(ctx, test.query, 100)
+			assert.Nil(t, results, "expected no results")
+			var queryErr *xkcdindex.QueryError
+			require.ErrorAs(t, err, &queryErr, "expected a QueryError")
+			assert.Equal(t, test.query, queryErr.Query, "expected error to hold the query")
+			assert.Equal(t, test.pos, queryErr.Pos, "expected error to point at the error position")
+			assert.Contains(t, queryErr.Message, test.message, "expected error to have correct message")
+		})
+	}
+}
+
+// searchPostJSON returns the API response of a post with the given searchable fields.
+func searchPostJSON(t *testing.T, num uint, title, alt, transcript, news string) string {
+	t.Helper()
+	b, err := json.Marshal(map[string]any{
+		"num":        num,
+		"title":      title,
+		"img":        fmt.Sprintf("https://imgs.xkcd.com/comics/%d.png", num),
+		"alt":        alt,
+		"transcript": transcript,
+		"news":       news,
+		"day":        "1",
+		"month":      "1",
+		"year":       "2010",
+	})
+	require.NoError(t, err)
+	return string(b)
+}
+
+// indexSearchPosts indexes the posts of the given API responses into idx.
+func indexSearchPosts(t *testing.T, idx *xkcdindex.Index, records ...string) {
+	t.Helper()
+	client := getPostsClient(t, records...)
+	for _, record := range records {
+		var post struct {
+			Num uint `json:"num"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(record), &post))
+		require.NoError(t, idx.Update(context.Background(), client, post.Num, post.Num, 1), "expected no error while indexing posts")
+	}
+}
+
+func searchNums(t *testing.T, idx *xkcdindex.Index, query string) []uint {
+	t.Helper()
+	results, err := idx.Search(context.Background(), query, 100)
+	require.NoError(t, err, "expected no error")
+	nums := make([]uint, 0, len(results))
+	for _, result := range results {
+		nums = append(nums, result.Post.Num)
+	}
+	return nums
+}
+
+func TestIndex_Search_Table(t *testing.T) {
+	ctx := context.Background()
+	idx := getEmptyIndex(t, false)
+	conn, err := idx.DB().Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.ExecContext(ctx, "CREATE VIRTUAL TABLE temp.posts_vocab USING fts5vocab(main, posts_fts, row)")
+	require.NoError(t, err)
+	// termDocs returns how many rows of the search table hold term.
+	termDocs := func(t *testing.T, term string) int {
+		t.Helper()
+		docs := 0
+		row := conn.QueryRowContext(ctx, "SELECT coalesce(max(doc), 0) FROM temp.posts_vocab WHERE term = ?", term)
+		require.NoError(t, row.Scan(&docs))
+		return docs
+	}
+	checkIntegrity := func(t *testing.T) {
+		t.Helper()
+		_, err := conn.ExecContext(ctx, "INSERT INTO posts_fts (posts_fts) VALUES ('integrity-check')")
+		assert.NoError(t, err, "expected search table to match posts")
+	}
+
+	t.Run("inserted posts are indexed", func(t *testing.T) {
+		indexSearchPosts(
+			t,
+			idx,
+			searchPostJSON(t, 1, "Zebra", "", "", ""),
+			searchPostJSON(t, 2, "Lion", "A zebra", "", ""),
+			searchPostJSON(t, 3, "Tiger", "", "[[A zebra runs.]]", ""),
+		)
+		checkIntegrity(t)
+		assert.Equal(t, 3, termDocs(t, "zebra"), "expected a search row per post")
+		assert.ElementsMatch(t, []uint{1, 2, 3}, searchNums(t, idx, "zebra"), "expected posts to be found")
+	})
+
+	t.Run("re-indexed posts update their search row", func(t *testing.T) {
+		indexSearchPosts(t, idx, searchPostJSON(t, 1, "Giraffe", "", "", ""))
+		checkIntegrity(t)
+		assert.Equal(t, 2, termDocs(t, "zebra"), "expected previous content to be removed from search table")
+		assert.Equal(t, 1, termDocs(t, "giraff"), "expected new content to be in search table")
+		assert.ElementsMatch(t, []uint{2, 3}, searchNums(t, idx, "zebra"), "expected previous content not to be found")
+		assert.Equal(t, []uint{1}, searchNums(t, idx, "giraffe"), "expected new content to be found once")
+	})
+
+	t.Run("unchanged re-indexed posts are not duplicated", func(t *testing.T) {
+		for range 2 {
+			indexSearchPosts(t, idx, searchPostJSON(t, 1, "Giraffe", "", "", ""))
+		}
+		checkIntegrity(t)
+		assert.Equal(t, 1, termDocs(t, "giraff"), "expected a single search row")
+		assert.Equal(t, []uint{1}, searchNums(t, idx, "giraffe"), "expected post to be found once")
+	})
+
+	t.Run("deleted posts are removed", func(t *testing.T) {
+		_, err := conn.ExecContext(ctx, "DELETE FROM posts WHERE num = 3")
+		require.NoError(t, err)
+		checkIntegrity(t)
+		assert.Equal(t, 1, termDocs(t, "zebra"), "expected search row to be removed")
+		assert.Equal(t, []uint{2}, searchNums(t, idx, "zebra"), "expected deleted post not to be found")
+	})
+}
+
+func TestIndex_Search_Ranking(t *testing.T) {
+	ctx := context.Background()
+	idx := getEmptyIndex(t, false)
+	indexSearchPosts(
+		t,
+		idx,
+		searchPostJSON(t, 1, "Zebra", "", "", ""),
+		searchPostJSON(t, 2, "Lion", "A zebra", "", ""),
+		searchPostJSON(t, 3, "Tiger", "", "[[A zebra runs.]]", ""),
+		searchPostJSON(t, 4, "Bear", "", "", ""),
+	)
+
+	t.Run("fields are weighted", func(t *testing.T) {
+		results, err := idx.Search(ctx, "zebra", 100)
+		require.NoError(t, err, "expected no error")
+		require.Len(t, results, 3, "expected posts matching in any field")
+		assert.Equal(t, []uint{1, 2, 3}, searchNums(t, idx, "zebra"), "expected title, then alt text, then transcript matches")
+		assert.Greater(t, results[0].Score, results[1].Score, "expected title match to score higher than alt text match")
+		assert.Greater(t, results[1].Score, results[2].Score, "expected alt text match to score higher than transcript match")
+		assert.Greater(t, results[2].Score, 0.0, "expected scores to be positive")
+	})
+
+	t.Run("snippets highlight matches", func(t *testing.T) {
+		results, err := idx.Search(ctx, "zebra", 100)
+		require.NoError(t, err, "expected no error")
+		require.Len(t, results, 3, "expected posts matching in any field")
+		highlighted := xkcdindex.HighlightStart + "zebra" + xkcdindex.HighlightEnd
+		assert.Equal(t, xkcdindex.HighlightStart+"Zebra"+xkcdindex.HighlightEnd, results[0].Snippet, "expected title snippet")
+		assert.Equal(t, "A "+highlighted, results[1].Snippet, "expected alt text snippet")
+		assert.Equal(t, "[[A "+highlighted+" runs.]]", results[2].Snippet, "expected transcript snippet")
+	})
+
+	t.Run("negated terms are not ranked", func(t *testing.T) {
+		results, err := idx.Search(ctx, "zebra -title:zebra", 100)
+		require.NoError(t, err, "expected no error")
+		require.Len(t, results, 2, "expected title match to be excluded")
+		assert.Equal(t, uint(2), results[0].Post.Num, "expected alt text match first")
+		results, err = idx.Search(ctx, "-zebra", 100)
+		require.NoError(t, err, "expected no error")
+		require.Len(t, results, 1, "expected posts not matching")
+		assert.Equal(t, uint(4), results[0].Post.Num, "expected post without the term")
+		assert.Zero(t, results[0].Score, "expected no score without ranked term")
+		assert.Empty(t, results[0].Snippet, "expected no snippet without ranked term")
+	})
+}
+
+func TestIndex_Search_NonASCII(t *testing.T) {
+	idx := getEmptyIndex(t, false)
+	indexSearchPosts(
+		t,
+		idx,
+		searchPostJSON(t, 1, "Voilà", "", "", ""),
+		searchPostJSON(t, 2, "Ålesund", "Voilà, Å.", "", ""),
+	)
+	assert.ElementsMatch(t, []uint{1, 2}, searchNums(t, idx, "voilà"), "expected words ending with a multibyte character to match")
+	assert.Equal(t, []uint{2}, searchNums(t, idx, "title:Ålesund"), "expected words starting with a multibyte character to match")
+	assert.Equal(t, []uint{2}, searchNums(t, idx, "voilà Å"), "expected multibyte spaces to separate words")
+}
@@ -0,0 +1,271 @@
+package xkcdindex
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/alitto/pond/v2"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+// Update updates the index with posts in range [start..end], with workers concurrent workers.
+// Posts which failed to be indexed by previous updates are retried along, once their retry delay is over.
+// Posts which fail to be indexed are recorded, see Index.Failures, and do not fail the update.
+func (i *Index) Update(ctx context.Context, client *xkcd.Client, start, end, workers uint) error {
+	if start > end {
+		return fmt.Errorf("start must be less than or equal to end")
+	}
+	if start <= 0 {
+		return fmt.Errorf("start must be greater than zero")
+	}
+	known, err := i.knownAbsences(ctx)
+	if err != nil {
+		return err
+	}
+	due, err := i.dueFailures(ctx)
+	if err != nil {
+		return err
+	}
+	var nums []uint
+	for _, num := range due {
+		if num < start || num > end {
+			nums = append(nums, num)
+		}
+	}
+	for num := start; num <= end; num++ {
+		if !known[num] {
+			nums = append(nums, num)
+		}
+	}
+	logger := i.logger.With(
+		slog.Uint64("start", uint64(start)),
+		slog.Uint64("end", uint64(end)),
+		slog.Int("retries", len(due)),
+		slog.Uint64("workers", uint64(workers)),
+	)
+	return i.update(ctx, client, nums, workers, true, logger)
+}
+
+// RetryFailures retries posts which failed to be indexed by previous updates, and which retry delay is over,
+// with workers concurrent workers.
+func (i *Index) RetryFailures(ctx context.Context, client *xkcd.Client, workers uint) error {
+	due, err := i.dueFailures(ctx)
+	if err != nil {
+		return err
+	}
+	if len(due) == 0 {
+		return nil
+	}
+	logger := i.logger.With(
+		slog.Int("retries", len(due)),
+		slog.Uint64("workers", uint64(workers)),
+	)
+	return i.update(ctx, client, due, workers, false, logger)
+}
+
+// update indexes posts nums, with workers concurrent workers, and updates last_update if setLastUpdate is true.
+// Posts which fail to be indexed are recorded apart from the update transaction, so that they are kept if the
+// update is rolled back.
+func (i *Index) update(ctx context.Context, client *xkcd.Client, nums []uint, workers uint, setLastUpdate bool, logger *slog.Logger) error {
+	startTime := time.Now()
+	logger.Debug("updating index")
+	tx, errBegin := i.db.BeginTx(ctx, nil)
+	if errBegin != nil {
+		return fmt.Errorf("failed to start transaction: %w", errBegin)
+	}
+	count := new(uint32)
+	failures := &updateFailures{}
+	pool := pond.NewPool(int(workers), pond.WithContext(ctx))
+	for _, num := range nums {
+		pool.SubmitErr(i.handleUpdate(ctx, pool, client, tx, num, count, failures))
+	}
+	pool.StopAndWait()
+
+	err := commitUpdate(ctx, tx, pool, setLastUpdate, logger)
+	// Failures are recorded even if the update timed out.
+	if errRecord := i.recordFailures(context.WithoutCancel(ctx), failures.failures); errRecord != nil {
+		return errors.Join(err, errRecord)
+	}
+	if err != nil {
+		return err
+	}
+	if n := len(failures.failures); n > 0 {
+		logger.Warn("some posts failed to be indexed, they will be retried by next updates", slog.Int("posts_failed", n))
+	}
+	logger.Debug(
+		"finished updating index",
+		slog.Duration("duration", time.Since(startTime)),
+		slog.Uint64("posts_updated", uint64(atomic.LoadUint32(count))),
+	)
+	return nil
+}
+
+// commitUpdate commits the update transaction tx once pool is done, updating last_update if setLastUpdate is
+// true, or rolls it back if the update failed.
+func commitUpdate(ctx context.Context, tx *sql.Tx, pool pond.Pool, setLastUpdate bool, logger *slog.Logger) error {
+	var err error
+
+	defer func(err *error) {
+		if *err == nil {
+			return
+		}
+		if errRollback := tx.Rollback(); errRollback != nil && !errors.Is(errRollback, sql.ErrTxDone) {
+			logger.Warn("failed to rollback transaction", slog.Any("error", errRollback))
+		}
+	}(&err)
+
+	dl, ok := ctx.Deadline()
+	if ok && dl.Before(time.Now()) {
+		err = fmt.Errorf("update failed due to timeout, consider increasing it with `--timeout / -t`")
+		return err
+	}
+
+	if pool.FailedTasks() > 0 {
+		err = errors.New("updating index failed")
+		return err
+	}
+
+	if setLastUpdate {
+		lastNum := uint(0)
+		row := tx.QueryRowContext(ctx, "SELECT coalesce(max(num), 0) FROM posts")
+		if row.Err() != nil && !errors.Is(row.Err(), sql.ErrNoRows) {
+			err = fmt.Errorf("failed to get last post number: %w", row.Err())
+			return err
+		}
+		if row.Err() == nil {
+			if err = row.Scan(&lastNum); err != nil {
+				err = fmt.Errorf("failed to get last post number: %w", err)
+				return err
+			}
+		}
+
+		if _, err = tx.ExecContext(ctx, "UPDATE last_update SET date = ?, last_num = ?", time.Now().Unix(), lastNum); err != nil {
+			err = fmt.Errorf("failed to update last_update: %w", err)
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		err = fmt.Errorf("failed to commit transaction: %w", err)
+		return err
+	}
+	return nil
+}
+
+// Execer is an interface for the database's ExecContext method.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func (i *Index) handleUpdate(
+	ctx context.Context,
+	pool pond.Pool,
+	client *xkcd.Client,
+	tx Execer,
+	num uint,
+	count *uint32,
+	failures *updateFailures,
+) func() error {
+	return func() error {
+		if pool.FailedTasks() > 0 {
+			return nil
+		}
+		log := i.logger.With(slog.Uint64("num", uint64(num)))
+		fail := func(class FailureClass, err error) error {
+			log.Warn("failed to index post", slog.String("class", string(class)), slog.String("error", err.Error()))
+			failures.add(num, class, err)
+			return nil
+		}
+		log.Debug("getting post")
+		post, err := client.GetPost(ctx, num)
+		if err != nil {
+			return fail(failureClass(err), err)
+		}
+		var data []byte
+		if i.offline {
+			data, err = i.readImageContent(ctx, post)
+			if err != nil {
+				return fail(FailureImage, err)
+			}
+		}
+		if err := upsertPost(ctx, post, data, tx, log); err != nil {
+			return err
+		}
+		if err := clearFailure(ctx, tx, num); err != nil {
+			return err
+		}
+		atomic.AddUint32(count, 1)
+		return nil
+	}
+}
+
+func (i *Index) indexPost(ctx context.Context, post *xkcd.Post, tx Execer, log *slog.Logger) error {
+	var data []byte
+	if i.offline {
+		b, err := i.readImageContent(ctx, post)
+		if err != nil {
+			log.Warn("failed to get image content", slog.String("error", err.Error()))
+			return nil
+		}
+		data = b
+	}
+	return upsertPost(ctx, post, data, tx, log)
+}
+
+// upsertPost inserts or updates a post in index, with its image content if data is not nil.
+// An image already stored for the post is kept if data is nil.
+func upsertPost(ctx context.Context, post *xkcd.Post, data []byte, tx Execer, log *slog.Logger) error {
+	var meta ImageMetadata
+	var content, contentType, sum, size any
+	if data != nil {
+		meta = newImageMetadata(data)
+		content, contentType, sum, size = data, meta.ContentType, meta.SHA256, meta.Size
+	}
+	// An upsert rather than a REPLACE, so that search table triggers see an update instead of a silent delete.
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO posts
+			(num, title, image, link, date, alt_text, transcript, news, content,
+			content_type, content_size, content_width, content_height, content_sha256)
+		VALUES
+			 (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (num) DO UPDATE SET
+			title = excluded.title,
+			image = excluded.image,
+			link = excluded.link,
+			date = excluded.date,
+			alt_text = excluded.alt_text,
+			transcript = excluded.transcript,
+			news = excluded.news,
+			content = coalesce(excluded.content, posts.content),
+			content_type = iif(excluded.content IS NULL, posts.content_type, excluded.content_type),
+			content_size = iif(excluded.content IS NULL, posts.content_size, excluded.content_size),
+			content_width = iif(excluded.content IS NULL, posts.content_width, excluded.content_width),
+			content_height = iif(excluded.content IS NULL, posts.content_height, excluded.content_height),
+			content_sha256 = iif(excluded.content IS NULL, posts.content_sha256, excluded.content_sha256);`,
+		post.Num,
+		post.Title,
+		post.Img,
+		post.Link,
+		post.Date.Unix(),
+		post.Alt,
+		post.Transcript,
+		post.News,
+		content,
+		contentType,
+		size,
+		nullableInt(meta.Width),
+		nullableInt(meta.Height),
+		sum,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert or update post: %w", err)
+	}
+	log.Debug("created post in index")
+	return nil
+}
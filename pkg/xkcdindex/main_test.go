@@ -0,0 +1,184 @@
+package xkcdindex_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+// testPostsCount is the number of posts served by the test API.
+const testPostsCount = 30
+
+var testTitles = []string{
+	"Barrel",
+	"Petit Trees",
+	"Island",
+	"Landscape",
+	"Blown Apart",
+	"Irony",
+	"Girl Sleeping",
+	"Red Spiders",
+	"Serenity",
+	"Pi Equals",
+}
+
+type mockRoundTripper func(*http.Request) (*http.Response, error)
+
+func (rt mockRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return rt(req)
+}
+
+// getTestImageResponse serves images of test posts: odd posts have a PNG image, even ones a JPEG image.
+func getTestImageResponse(t testing.TB, path string) (*http.Response, error) {
+	t.Helper()
+	var num int
+	_, err := fmt.Sscanf(path, "/comics/%d.png", &num)
+	require.NoError(t, err)
+	file, contentType := "../xkcd/testdata/image.png", "image/png"
+	if num%2 == 0 {
+		file, contentType = "../xkcd/testdata/image.jpg", "image/jpeg"
+	}
+	f, err := os.Open(file)
+	require.NoError(t, err)
+	hdr := http.Header{}
+	hdr.Set("Content-Type", contentType)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     hdr,
+		Body:       f,
+	}, nil
+}
+
+// testPostJSON returns the API response of test post num: posts are published on the first day
+// of a month, ten posts a year from 2006, odd posts have a transcript.
+func testPostJSON(num int) string {
+	transcript := ""
+	if num%2 == 1 {
+		transcript = fmt.Sprintf(`[[A boy sits in a barrel.]]\nBoy: I wonder about the %s.`, strings.ToLower(testTitles[num%10]))
+	}
+	return fmt.Sprintf(
+		`{"month": "%d", "num": %d, "link": "", "year": "%d", "news": "", "safe_title": "%[4]s", "transcript": "%s", "alt": "Alt text of post %[2]d.", "img": "https://imgs.xkcd.com/comics/%[2]d.png", "title": "%[4]s", "day": "1"}`,
+		(num-1)%10+1,
+		num,
+		2006+(num-1)/10,
+		testTitles[num%10],
+		transcript,
+	)
+}
+
+// getPostsClient returns a client serving the given API post records, by their post number.
+func getPostsClient(t testing.TB, records ...string) *xkcd.Client {
+	t.Helper()
+	posts := make(map[string]string, len(records))
+	for _, record := range records {
+		var post struct {
+			Num json.Number `json:"num"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(record), &post))
+		posts["/"+post.Num.String()+"/info.0.json"] = record
+	}
+	return xkcd.New(
+		xkcd.WithClient(&http.Client{Transport: mockRoundTripper(func(r *http.Request) (*http.Response, error) {
+			record, ok := posts[r.URL.Path]
+			if !ok {
+				return &http.Response{
+					StatusCode: http.StatusNotFound,
+					Body:       io.NopCloser(strings.NewReader("404 Not Found")),
+				}, nil
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(record)),
+			}, nil
+		})}),
+	)
+}
+
+func getTestClient(t testing.TB) *xkcd.Client {
+	t.Helper()
+	return xkcd.New(xkcd.WithClient(getTestHTTPClient(t)))
+}
+
+// getTestHTTPClient returns a http client serving the test API and images.
+func getTestHTTPClient(t testing.TB) *http.Client {
+	t.Helper()
+	return &http.Client{Transport: mockRoundTripper(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Host == "imgs.xkcd.com" {
+			return getTestImageResponse(t, r.URL.Path)
+		}
+		var num int
+		if _, err := fmt.Sscanf(r.URL.Path, "/%d/info.0.json", &num); err != nil {
+			num = testPostsCount
+		}
+		if num < 1 || num > testPostsCount {
+			return &http.Response{
+				StatusCode: http.StatusNotFound,
+				Body:       io.NopCloser(strings.NewReader("404 Not Found")),
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(testPostJSON(num))),
+		}, nil
+	})}
+}
+
+func getLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// getTestIndex returns an initialized online index, holding all test posts.
+func getTestIndex(t testing.TB) *xkcdindex.Index {
+	t.Helper()
+	return getTestIndexWithMode(t, false)
+}
+
+// getTestIndexWithMode returns an initialized index in the given offline mode, holding all test posts.
+func getTestIndexWithMode(t testing.TB, offline bool) *xkcdindex.Index {
+	t.Helper()
+	ctx := context.Background()
+	idx, err := xkcdindex.New(
+		filepath.Join(t.TempDir(), "test.index"),
+		getLogger(),
+		xkcdindex.WithHTTPClient(getTestHTTPClient(t)),
+	)
+	require.NoError(t, err)
+	require.NoError(t, idx.Init(ctx, false, offline))
+	require.NoError(t, idx.Update(ctx, getTestClient(t), 1, testPostsCount, 4))
+	t.Cleanup(func() {
+		require.NoError(t, idx.Close())
+	})
+	return idx
+}
+
+// getEmptyIndex returns an initialized index in the given offline mode, holding no posts.
+func getEmptyIndex(t testing.TB, offline bool) *xkcdindex.Index {
+	t.Helper()
+	idx, err := xkcdindex.New(filepath.Join(t.TempDir(), "empty.index"), getLogger())
+	require.NoError(t, err)
+	require.NoError(t, idx.Init(context.Background(), false, offline))
+	t.Cleanup(func() {
+		require.NoError(t, idx.Close())
+	})
+	return idx
+}
+
+func postNums(posts []*xkcd.Post) []uint {
+	nums := make([]uint, 0, len(posts))
+	for _, post := range posts {
+		nums = append(nums, post.Num)
+	}
+	return nums
+}
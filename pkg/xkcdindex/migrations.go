@@ -0,0 +1,331 @@
+package xkcdindex
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+)
+
+// Migration is a step of the index schema evolution.
+type Migration struct {
+	// Version is the schema version of the index once the migration is applied.
+	Version uint `json:"version"`
+	// Description describes what the migration does.
+	Description string `json:"description"`
+
+	up func(ctx context.Context, tx *sql.Tx) error
+}
+
+// migrations are all the index schema migrations, ordered by version.
+// Released migrations must never be modified, schema changes are made by adding a new one.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "create posts, last_update and settings tables",
+		up:          migrateInitialSchema,
+	},
+	{
+		Version:     2,
+		Description: "create full-text search table",
+		up:          migrateSearchTable,
+	},
+	{
+		Version:     3,
+		Description: "add stored images metadata",
+		up:          migrateImageMetadata,
+	},
+	{
+		Version:     4,
+		Description: "create known absences table",
+		up:          migrateKnownAbsences,
+	},
+	{
+		Version:     5,
+		Description: "create failed posts table",
+		up:          migrateFailedPosts,
+	},
+	{
+		Version:     6,
+		Description: "update full-text search table only when searchable columns change",
+		up:          migrateSearchUpdateTrigger,
+	},
+}
+
+// SchemaVersion is the schema version of indexes created or migrated by this package.
+func SchemaVersion() uint {
+	return migrations[len(migrations)-1].Version
+}
+
+// schemaVersion returns the schema version of the index database.
+func schemaVersion(ctx context.Context, db *sql.DB) (uint, error) {
+	var tables int
+	row := db.QueryRowContext(ctx, "SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name IN ('posts', 'settings')")
+	if err := row.Scan(&tables); err != nil {
+		return 0, fmt.Errorf("failed to read index tables: %w", err)
+	}
+	if tables == 0 {
+		return 0, nil
+	}
+	var value string
+	row = db.QueryRowContext(ctx, "SELECT value FROM settings WHERE name = 'schema_version' LIMIT 1")
+	if err := row.Scan(&value); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Indexes created before schema versioning have the initial schema.
+			return 1, nil
+		}
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	version, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schema version %q: %w", value, err)
+	}
+	return uint(version), nil
+}
+
+// PendingMigrations returns the migrations that are not applied yet to the index, in the order they will be applied.
+func (i *Index) PendingMigrations(ctx context.Context) ([]Migration, error) {
+	if i.db == nil {
+		return nil, nil
+	}
+	return pendingMigrations(ctx, i.db)
+}
+
+func pendingMigrations(ctx context.Context, db *sql.DB) ([]Migration, error) {
+	version, err := schemaVersion(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if version > SchemaVersion() {
+		return nil, fmt.Errorf(
+			"index schema version %d is newer than the supported version %d, upgrade xkcd to use it",
+			version,
+			SchemaVersion(),
+		)
+	}
+	var pending []Migration
+	for _, m := range migrations {
+		if m.Version > version {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate applies the pending migrations to the index, and returns them.
+// Each migration is applied in its own transaction, so a failing migration leaves
+// the index with the schema of the last migration that succeeded.
+func (i *Index) Migrate(ctx context.Context) ([]Migration, error) {
+	if i.db == nil {
+		return nil, errors.New("index is not initialized")
+	}
+	pending, err := pendingMigrations(ctx, i.db)
+	if err != nil {
+		return nil, err
+	}
+	for k, m := range pending {
+		if err := i.applyMigration(ctx, m); err != nil {
+			return pending[:k], err
+		}
+	}
+	return pending, nil
+}
+
+func (i *Index) applyMigration(ctx context.Context, m Migration) error {
+	logger := i.logger.With(slog.Uint64("version", uint64(m.Version)), slog.String("migration", m.Description))
+	logger.Debug("applying migration")
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if errRollback := tx.Rollback(); errRollback != nil && !errors.Is(errRollback, sql.ErrTxDone) {
+			logger.Warn("failed to rollback transaction", slog.Any("error", errRollback))
+		}
+	}()
+	if err := m.up(ctx, tx); err != nil {
+		return fmt.Errorf("failed to apply migration to version %d (%s): %w", m.Version, m.Description, err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM settings WHERE name = 'schema_version'"); err != nil {
+		return fmt.Errorf("failed to update schema version: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO settings (name, value) VALUES ('schema_version', ?)", strconv.FormatUint(uint64(m.Version), 10))
+	if err != nil {
+		return fmt.Errorf("failed to update schema version: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration to version %d: %w", m.Version, err)
+	}
+	logger.Debug("applied migration")
+	return nil
+}
+
+func migrateInitialSchema(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`create table posts
+(
+    num        INTEGER NOT NULL
+        CONSTRAINT posts_pk
+            primary key,
+    title      TEXT    NOT NULL,
+    image      TEXT    NOT NULL,
+    link       TEXT    NOT NULL,
+    date       INTEGER NOT NULL,
+    alt_text   TEXT    NOT NULL,
+    transcript TEXT    NOT NULL,
+    news       TEXT    NOT NULL,
+	content    BLOB    null,
+
+    CONSTRAINT date_check
+        check (date > 0),
+    CONSTRAINT num_check
+        check (num > 0)
+)`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create posts table: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, "CREATE INDEX posts_content_index ON posts(title, alt_text, transcript, news);")
+	if err != nil {
+		return fmt.Errorf("failed to create content index: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, "CREATE TABLE last_update (date INTEGER NOT NULL, last_num INTEGER NOT NULL)")
+	if err != nil {
+		return fmt.Errorf("failed to create last_update table: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, "CREATE TABLE settings (name TEXT NOT NULL, value TEXT NOT NULL)")
+	if err != nil {
+		return fmt.Errorf("failed to create settings table: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO settings (name, value) VALUES ('offline', '0')")
+	if err != nil {
+		return fmt.Errorf("failed to set offline setting: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO last_update (date, last_num) VALUES (0, 0)")
+	if err != nil {
+		return fmt.Errorf("failed to set last_update: %w", err)
+	}
+	return nil
+}
+
+// migrateSearchTable creates the full-text search table and the triggers keeping it in sync with posts,
+// and fills it with already indexed posts.
+func migrateSearchTable(ctx context.Context, tx *sql.Tx) error {
+	// Indexes created before schema versioning may already have the search table.
+	_, err := tx.ExecContext(
+		ctx,
+		`CREATE VIRTUAL TABLE IF NOT EXISTS posts_fts USING fts5(
+	title,
+	alt_text,
+	transcript,
+	news,
+	content='posts',
+	content_rowid='num',
+	tokenize='porter unicode61 remove_diacritics 2'
+)`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create search table: %w", err)
+	}
+	triggers := []string{
+		`CREATE TRIGGER IF NOT EXISTS posts_fts_insert AFTER INSERT ON posts BEGIN
+	INSERT INTO posts_fts (rowid, title, alt_text, transcript, news)
+		VALUES (new.num, new.title, new.alt_text, new.transcript, new.news);
+END`,
+		`CREATE TRIGGER IF NOT EXISTS posts_fts_delete AFTER DELETE ON posts BEGIN
+	INSERT INTO posts_fts (posts_fts, rowid, title, alt_text, transcript, news)
+		VALUES ('delete', old.num, old.title, old.alt_text, old.transcript, old.news);
+END`,
+		`CREATE TRIGGER IF NOT EXISTS posts_fts_update AFTER UPDATE ON posts BEGIN
+	INSERT INTO posts_fts (posts_fts, rowid, title, alt_text, transcript, news)
+		VALUES ('delete', old.num, old.title, old.alt_text, old.transcript, old.news);
+	INSERT INTO posts_fts (rowid, title, alt_text, transcript, news)
+		VALUES (new.num, new.title, new.alt_text, new.transcript, new.news);
+END`,
+	}
+	for _, trigger := range triggers {
+		if _, err = tx.ExecContext(ctx, trigger); err != nil {
+			return fmt.Errorf("failed to create search trigger: %w", err)
+		}
+	}
+	if _, err = tx.ExecContext(ctx, "INSERT INTO posts_fts (posts_fts) VALUES ('rebuild')"); err != nil {
+		return fmt.Errorf("failed to build search table: %w", err)
+	}
+	return nil
+}
+
+// migrateImageMetadata adds the metadata columns of images stored in offline indexes.
+// Existing images metadata is computed by Index.BackfillImageMetadata.
+func migrateImageMetadata(ctx context.Context, tx *sql.Tx) error {
+	for _, column := range []string{
+		"content_type TEXT NULL",
+		"content_size INTEGER NULL",
+		"content_width INTEGER NULL",
+		"content_height INTEGER NULL",
+		"content_sha256 TEXT NULL",
+	} {
+		if _, err := tx.ExecContext(ctx, "ALTER TABLE posts ADD COLUMN "+column); err != nil {
+			return fmt.Errorf("failed to add image metadata column: %w", err)
+		}
+	}
+	return nil
+}
+
+// migrateKnownAbsences creates the table of post numbers that were never published, so that they are not
+// reported as missing posts, and records comic #404.
+func migrateKnownAbsences(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, "CREATE TABLE known_absences (num INTEGER NOT NULL PRIMARY KEY, reason TEXT NOT NULL)")
+	if err != nil {
+		return fmt.Errorf("failed to create known_absences table: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO known_absences (num, reason) VALUES (404, 'comic #404 is not found, on purpose')")
+	if err != nil {
+		return fmt.Errorf("failed to record known absence: %w", err)
+	}
+	return nil
+}
+
+// migrateFailedPosts creates the table of posts which failed to be indexed, and are retried by updates.
+func migrateFailedPosts(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`CREATE TABLE failed_posts
+(
+    num             INTEGER NOT NULL PRIMARY KEY,
+    error_class     TEXT    NOT NULL,
+    error           TEXT    NOT NULL,
+    attempts        INTEGER NOT NULL,
+    first_failed_at INTEGER NOT NULL,
+    last_failed_at  INTEGER NOT NULL,
+    next_retry_at   INTEGER NOT NULL
+)`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create failed_posts table: %w", err)
+	}
+	return nil
+}
+
+// migrateSearchUpdateTrigger recreates the trigger keeping the full-text search table in sync with updated posts,
+// so that it only fires when searchable columns change, and not when images are stored or removed.
+func migrateSearchUpdateTrigger(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, "DROP TRIGGER IF EXISTS posts_fts_update"); err != nil {
+		return fmt.Errorf("failed to drop search trigger: %w", err)
+	}
+	_, err := tx.ExecContext(
+		ctx,
+		`CREATE TRIGGER posts_fts_update AFTER UPDATE OF title, alt_text, transcript, news ON posts BEGIN
+	INSERT INTO posts_fts (posts_fts, rowid, title, alt_text, transcript, news)
+		VALUES ('delete', old.num, old.title, old.alt_text, old.transcript, old.news);
+	INSERT INTO posts_fts (rowid, title, alt_text, transcript, news)
+		VALUES (new.num, new.title, new.alt_text, new.transcript, new.news);
+END`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create search trigger: %w", err)
+	}
+	return nil
+}
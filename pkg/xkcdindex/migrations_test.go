@@ -0,0 +1,114 @@
+package xkcdindex_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+// createUnversionedIndex creates an index file as created before schema versioning, holding one post.
+func createUnversionedIndex(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.index")
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer db.Close()
+	for _, query := range []string{
+		`CREATE TABLE posts (num INTEGER NOT NULL CONSTRAINT posts_pk PRIMARY KEY, title TEXT NOT NULL, image TEXT NOT NULL, link TEXT NOT NULL, date INTEGER NOT NULL, alt_text TEXT NOT NULL, transcript TEXT NOT NULL, news TEXT NOT NULL, content BLOB NULL)`,
+		`CREATE INDEX posts_content_index ON posts(title, alt_text, transcript, news)`,
+		`CREATE TABLE last_update (date INTEGER NOT NULL, last_num INTEGER NOT NULL)`,
+		`CREATE TABLE settings (name TEXT NOT NULL, value TEXT NOT NULL)`,
+		`INSERT INTO settings (name, value) VALUES ('offline', '0')`,
+		`INSERT INTO last_update (date, last_num) VALUES (1700000000, 1)`,
+		`INSERT INTO posts VALUES (1, 'Barrel - Part 1', 'https://imgs.xkcd.com/comics/barrel_cropped_(1).jpg', 'https://xkcd.com/1/', 1136073600, 'Don''t we all.', '', '', NULL)`,
+	} {
+		_, err := db.Exec(query)
+		require.NoError(t, err)
+	}
+	return path
+}
+
+func TestIndex_Migrate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("new index is up to date", func(t *testing.T) {
+		idx := getTestIndex(t)
+		pending, err := idx.PendingMigrations(ctx)
+		assert.NoError(t, err, "expected no error")
+		assert.Empty(t, pending, "expected no pending migration")
+	})
+
+	t.Run("unversioned index is migrated when opened", func(t *testing.T) {
+		idx, err := xkcdindex.New(createUnversionedIndex(t), getLogger())
+		require.NoError(t, err, "expected no error")
+		defer idx.Close()
+		pending, err := idx.PendingMigrations(ctx)
+		assert.NoError(t, err, "expected no error")
+		assert.Empty(t, pending, "expected no pending migration")
+		results, err := idx.Search(ctx, "barrel", 10)
+		require.NoError(t, err, "expected search to work on migrated index")
+		require.Len(t, results, 1, "expected existing posts to be searchable")
+		_, lastNum, err := idx.GetLastUpdate()
+		assert.NoError(t, err, "expected no error")
+		assert.Equal(t, uint(1), lastNum, "expected data to be kept")
+	})
+
+	t.Run("migrations are not applied when disabled", func(t *testing.T) {
+		idx, err := xkcdindex.New(createUnversionedIndex(t), getLogger(), xkcdindex.WithAutoMigrate(false))
+		require.NoError(t, err, "expected no error")
+		defer idx.Close()
+		pending, err := idx.PendingMigrations(ctx)
+		require.NoError(t, err, "expected no error")
+		require.NotEmpty(t, pending, "expected pending migrations")
+		assert.Equal(t, uint(2), pending[0].Version, "expected migrations after initial schema to be pending")
+		assert.Equal(t, xkcdindex.SchemaVersion(), pending[len(pending)-1].Version, "expected migrations up to current version to be pending")
+
+		applied, err := idx.Migrate(ctx)
+		assert.NoError(t, err, "expected no error")
+		require.Len(t, applied, len(pending), "expected pending migrations to be applied")
+		for k := range pending {
+			assert.Equal(t, pending[k].Version, applied[k].Version, "expected pending migrations to be applied in order")
+		}
+		pending, err = idx.PendingMigrations(ctx)
+		assert.NoError(t, err, "expected no error")
+		assert.Empty(t, pending, "expected no pending migration")
+	})
+
+	t.Run("search table is only updated with searchable columns", func(t *testing.T) {
+		idx, err := xkcdindex.New(createUnversionedIndex(t), getLogger())
+		require.NoError(t, err, "expected no error")
+		defer idx.Close()
+		var trigger string
+		row := idx.DB().QueryRowContext(ctx, "SELECT sql FROM sqlite_master WHERE type = 'trigger' AND name = 'posts_fts_update'")
+		require.NoError(t, row.Scan(&trigger), "expected search update trigger to exist")
+		assert.Contains(t, trigger, "AFTER UPDATE OF title, alt_text, transcript, news ON posts", "expected trigger to be limited to searchable columns")
+
+		_, err = idx.DB().ExecContext(ctx, "UPDATE posts SET title = 'Island' WHERE num = 1")
+		require.NoError(t, err)
+		results, err := idx.Search(ctx, "island", 10)
+		require.NoError(t, err, "expected no error")
+		assert.Len(t, results, 1, "expected updated title to be searchable")
+		results, err = idx.Search(ctx, "barrel", 10)
+		require.NoError(t, err, "expected no error")
+		assert.Empty(t, results, "expected previous title not to be searchable")
+	})
+
+	t.Run("newer index is rejected", func(t *testing.T) {
+		path := createUnversionedIndex(t)
+		db, err := sql.Open("sqlite", path)
+		require.NoError(t, err)
+		_, err = db.Exec("INSERT INTO settings (name, value) VALUES ('schema_version', '999')")
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		idx, err := xkcdindex.New(path, getLogger())
+		assert.Nil(t, idx, "expected no index")
+		assert.ErrorContains(t, err, "index schema version 999 is newer than the supported version", "expected error to be explicit")
+	})
+}
@@ -0,0 +1,246 @@
+package xkcdindex
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alitto/pond/v2"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+// Offline returns true if the index stores post images for offline use.
+func (i *Index) Offline() bool {
+	return i.offline
+}
+
+func (i *Index) setOffline(ctx context.Context, offline bool) error {
+	offlineVal := "0"
+	if offline {
+		offlineVal = "1"
+	}
+	if _, err := i.db.ExecContext(ctx, "UPDATE settings SET value = ? WHERE name = 'offline'", offlineVal); err != nil {
+		return fmt.Errorf("failed to set offline setting: %w", err)
+	}
+	i.offline = offline
+	return nil
+}
+
+// ImageVariant returns the variant of post images stored in offline mode.
+func (i *Index) ImageVariant() xkcd.ImageVariant {
+	return i.imageVariant
+}
+
+// SetImageVariant sets the variant of post images stored in offline mode, Variant1x by default.
+// Posts without the variant have their Variant1x image stored instead. Already stored images are kept,
+// disable then enable offline mode to store them again with the new variant.
+func (i *Index) SetImageVariant(ctx context.Context, variant xkcd.ImageVariant) error {
+	if i.db == nil {
+		return errors.New("index is not initialized")
+	}
+	if _, err := i.db.ExecContext(ctx, "DELETE FROM settings WHERE name = 'image_variant'"); err != nil {
+		return fmt.Errorf("failed to set image variant setting: %w", err)
+	}
+	_, err := i.db.ExecContext(ctx, "INSERT INTO settings (name, value) VALUES ('image_variant', ?)", variant.String())
+	if err != nil {
+		return fmt.Errorf("failed to set image variant setting: %w", err)
+	}
+	i.imageVariant = variant
+	return nil
+}
+
+// readImageVariant reads the image variant setting, which indexes created before it was added do not have.
+func readImageVariant(db *sql.DB) (xkcd.ImageVariant, error) {
+	var value string
+	err := db.QueryRow("SELECT value FROM settings WHERE name = 'image_variant' LIMIT 1").Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return xkcd.Variant1x, nil
+	}
+	if err != nil {
+		return xkcd.Variant1x, fmt.Errorf("failed to read image variant setting: %w", err)
+	}
+	return xkcd.ParseImageVariant(value)
+}
+
+// EnableOffline switches the index to offline mode, and stores the images of indexed posts
+// which are not stored yet, with workers concurrent workers. It returns how many images were stored.
+// Images are fetched with client, so that its mirrors, retry policy and limits apply.
+// Each image is stored as soon as it is fetched, so an interrupted or partially failed call can be
+// resumed by calling it again.
+func (i *Index) EnableOffline(ctx context.Context, client *xkcd.Client, workers uint) (uint, error) {
+	if i.db == nil {
+		return 0, errors.New("index is not initialized")
+	}
+	startTime := time.Now()
+	if err := i.setOffline(ctx, true); err != nil {
+		return 0, err
+	}
+	rows, err := i.db.QueryContext(ctx, "SELECT "+postColumns+" FROM posts WHERE content IS NULL ORDER BY num")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list posts without image: %w", err)
+	}
+	var pending []*xkcd.Post
+	for rows.Next() {
+		// Indexed posts are bound to client, rather than fetched again from the API.
+		post, _, err := i.scanPostContentWith(client, client.HTTPClient(), rows)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to list posts without image: %w", err)
+		}
+		pending = append(pending, post)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list posts without image: %w", err)
+	}
+	logger := i.logger.With(
+		slog.Int("pending", len(pending)),
+		slog.Uint64("workers", uint64(workers)),
+	)
+	logger.Debug("storing images for offline mode")
+
+	count := new(uint32)
+	failed := new(uint32)
+	// Images are fetched concurrently, but stored one at a time to avoid SQLite lock contention.
+	var writeLock sync.Mutex
+	pool := pond.NewPool(int(workers), pond.WithContext(ctx))
+	for _, post := range pending {
+		pool.Submit(func() {
+			log := logger.With(slog.Uint64("num", uint64(post.Num)))
+			if err := i.storeImage(ctx, post, &writeLock, log); err != nil {
+				log.Warn("failed to store image", slog.String("error", err.Error()))
+				atomic.AddUint32(failed, 1)
+				return
+			}
+			atomic.AddUint32(count, 1)
+		})
+	}
+	pool.StopAndWait()
+
+	stored := uint(atomic.LoadUint32(count))
+	dl, ok := ctx.Deadline()
+	if ok && dl.Before(time.Now()) {
+		return stored, fmt.Errorf("storing images failed due to timeout, consider increasing it with `--timeout / -t`, then resume")
+	}
+	if n := atomic.LoadUint32(failed); n > 0 {
+		return stored, fmt.Errorf("failed to store %d images, try again to resume", n)
+	}
+	logger.Debug(
+		"stored images for offline mode",
+		slog.Duration("duration", time.Since(startTime)),
+		slog.Uint64("images_stored", uint64(stored)),
+	)
+	return stored, nil
+}
+
+func (i *Index) storeImage(ctx context.Context, post *xkcd.Post, writeLock sync.Locker, log *slog.Logger) error {
+	log.Debug("getting image")
+	data, err := i.readImageContent(ctx, post)
+	if err != nil {
+		return err
+	}
+	writeLock.Lock()
+	defer writeLock.Unlock()
+	if err := storeImageContent(ctx, i.db, post.Num, data); err != nil {
+		return err
+	}
+	log.Debug("stored image")
+	return nil
+}
+
+// readImageContent fetches the image content of a post, in the index image variant.
+func (i *Index) readImageContent(ctx context.Context, post *xkcd.Post) ([]byte, error) {
+	rdr, err := post.GetImageContentVariant(ctx, i.imageVariant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image content: %w", err)
+	}
+	defer rdr.Close()
+	data, err := i.readImage(post.Img, rdr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image content: %w", err)
+	}
+	return data, nil
+}
+
+// readImage reads the content r of the image at imgURL, failing with an *xkcd.ImageTooLargeError if it is longer
+// than the maximum image size of the index.
+func (i *Index) readImage(imgURL string, r io.Reader) ([]byte, error) {
+	if i.maxImageBytes <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, i.maxImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > i.maxImageBytes {
+		return nil, &xkcd.ImageTooLargeError{URL: imgURL, MaxBytes: i.maxImageBytes}
+	}
+	return data, nil
+}
+
+// storeImageContent stores the image content of an indexed post, with its metadata.
+func storeImageContent(ctx context.Context, tx Execer, num uint, data []byte) error {
+	meta := newImageMetadata(data)
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE posts SET
+			content = ?,
+			content_type = ?,
+			content_size = ?,
+			content_width = ?,
+			content_height = ?,
+			content_sha256 = ?
+		WHERE num = ?`,
+		data,
+		meta.ContentType,
+		meta.Size,
+		nullableInt(meta.Width),
+		nullableInt(meta.Height),
+		meta.SHA256,
+		num,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store image: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("failed to store image: post %d is not indexed", num)
+	}
+	return nil
+}
+
+// DisableOffline switches the index to online mode, removes all stored images, and reclaims their space.
+func (i *Index) DisableOffline(ctx context.Context) error {
+	if i.db == nil {
+		return errors.New("index is not initialized")
+	}
+	if err := i.setOffline(ctx, false); err != nil {
+		return err
+	}
+	i.logger.Debug("removing stored images")
+	_, err := i.db.ExecContext(
+		ctx,
+		`UPDATE posts SET
+			content = NULL,
+			content_type = NULL,
+			content_size = NULL,
+			content_width = NULL,
+			content_height = NULL,
+			content_sha256 = NULL
+		WHERE content IS NOT NULL`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to remove stored images: %w", err)
+	}
+	i.logger.Debug("vacuuming index")
+	if _, err := i.db.ExecContext(ctx, "VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package xkcdindex_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+func TestIndex_EnableOffline(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.index")
+
+	idx, err := xkcdindex.New(path, getLogger())
+	require.NoError(t, err)
+	require.NoError(t, idx.Init(ctx, false, false))
+	require.NoError(t, idx.Update(ctx, getTestClient(t), 1, testPostsCount, 4))
+	require.NoError(t, idx.Close())
+
+	t.Run("partial failure", func(t *testing.T) {
+		working := getTestHTTPClient(t)
+		client := xkcd.New(xkcd.WithClient(&http.Client{
+			Transport: mockRoundTripper(func(r *http.Request) (*http.Response, error) {
+				if strings.HasSuffix(r.URL.Path, "0.png") {
+					return nil, errors.New("kaboom")
+				}
+				return working.Do(r)
+			}),
+		}))
+		idx, err := xkcdindex.New(path, getLogger())
+		require.NoError(t, err)
+		defer idx.Close()
+		stored, err := idx.EnableOffline(ctx, client, 4)
+		assert.ErrorContains(t, err, "failed to store 3 images", "expected failures to be reported")
+		assert.Equal(t, uint(testPostsCount-3), stored, "expected other images to be stored")
+		assert.True(t, idx.Offline(), "expected index to be offline")
+		meta, err := idx.GetImageMetadata(ctx, 10)
+		assert.NoError(t, err, "expected no error")
+		assert.Nil(t, meta, "expected failed image not to be stored")
+	})
+
+	t.Run("client limits", func(t *testing.T) {
+		idx, err := xkcdindex.New(path, getLogger())
+		require.NoError(t, err)
+		defer idx.Close()
+		client := xkcd.New(xkcd.WithClient(getTestHTTPClient(t)), xkcd.WithMaxImageBytes(16))
+		stored, err := idx.EnableOffline(ctx, client, 4)
+		assert.ErrorContains(t, err, "failed to store 3 images", "expected images larger than the client limit to fail")
+		assert.Zero(t, stored, "expected no image to be stored")
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		idx, err := xkcdindex.New(path, getLogger())
+		require.NoError(t, err)
+		defer idx.Close()
+		assert.True(t, idx.Offline(), "expected offline mode to be kept")
+		working := getTestHTTPClient(t)
+		var mu sync.Mutex
+		var requested []string
+		client := xkcd.New(xkcd.WithClient(&http.Client{
+			Transport: mockRoundTripper(func(r *http.Request) (*http.Response, error) {
+				mu.Lock()
+				requested = append(requested, r.URL.Host)
+				mu.Unlock()
+				return working.Do(r)
+			}),
+		}))
+		stored, err := idx.EnableOffline(ctx, client, 4)
+		assert.NoError(t, err, "expected no error")
+		assert.Equal(t, uint(3), stored, "expected only missing images to be stored")
+		assert.Equal(
+			t,
+			[]string{"imgs.xkcd.com", "imgs.xkcd.com", "imgs.xkcd.com"},
+			requested,
+			"expected only images to be requested, not indexed posts",
+		)
+		for _, num := range []uint{1, 10, 30} {
+			meta, err := idx.GetImageMetadata(ctx, num)
+			assert.NoError(t, err, "expected no error")
+			assert.NotNil(t, meta, "expected image to be stored")
+		}
+	})
+}
+
+func TestIndex_DisableOffline(t *testing.T) {
+	ctx := context.Background()
+	idx := getTestIndexWithMode(t, true)
+	require.True(t, idx.Offline())
+
+	require.NoError(t, idx.DisableOffline(ctx), "expected no error")
+	assert.False(t, idx.Offline(), "expected index to be online")
+	hasImage := true
+	posts, _, err := idx.Query(ctx, xkcdindex.Filter{HasOfflineImage: &hasImage}, xkcdindex.OrderNumAsc, xkcdindex.Page{})
+	assert.NoError(t, err, "expected no error")
+	assert.Empty(t, posts, "expected images to be removed")
+	meta, err := idx.GetImageMetadata(ctx, 1)
+	assert.NoError(t, err, "expected no error")
+	assert.Nil(t, meta, "expected image metadata to be removed")
+}
+
+func TestIndex_SetImageVariant(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.index")
+	idx, err := xkcdindex.New(path, getLogger())
+	require.NoError(t, err)
+	require.NoError(t, idx.Init(ctx, false, true))
+	assert.Equal(t, xkcd.Variant1x, idx.ImageVariant(), "expected 1x variant by default")
+	require.NoError(t, idx.SetImageVariant(ctx, xkcd.Variant2x), "expected no error")
+	require.NoError(t, idx.Close())
+
+	idx, err = xkcdindex.New(path, getLogger())
+	require.NoError(t, err)
+	defer idx.Close()
+	assert.Equal(t, xkcd.Variant2x, idx.ImageVariant(), "expected variant setting to be kept")
+
+	// Even posts have a 2x PNG image, instead of their 1x JPEG one, odd posts have no 2x image.
+	working := getTestHTTPClient(t)
+	client := xkcd.New(xkcd.WithClient(&http.Client{Transport: mockRoundTripper(func(r *http.Request) (*http.Response, error) {
+		var num int
+		if _, err := fmt.Sscanf(r.URL.Path, "/comics/%d_2x.png", &num); err == nil {
+			if num%2 == 1 {
+				return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody}, nil
+			}
+			r.URL.Path = fmt.Sprintf("/comics/%d.png", num+1)
+		}
+		return working.Do(r)
+	})}))
+	require.NoError(t, idx.Update(ctx, client, 1, 4, 2))
+	for _, num := range []uint{1, 2, 3, 4} {
+		meta, err := idx.GetImageMetadata(ctx, num)
+		require.NoError(t, err, "expected no error")
+		require.NotNil(t, meta, "expected image to be stored")
+		assert.Equal(t, "image/png", meta.ContentType, "expected 2x image, or 1x image of odd posts, to be stored")
+	}
+}
+
+func TestIndex_WithMaxImageBytes(t *testing.T) {
+	ctx := context.Background()
+	idx, err := xkcdindex.New(
+		filepath.Join(t.TempDir(), "test.index"),
+		getLogger(),
+		xkcdindex.WithHTTPClient(getTestHTTPClient(t)),
+		xkcdindex.WithMaxImageBytes(16),
+	)
+	require.NoError(t, err)
+	defer idx.Close()
+	require.NoError(t, idx.Init(ctx, false, true))
+
+	require.NoError(t, idx.Update(ctx, getTestClient(t), 1, 2, 2), "expected failures not to fail update")
+	failures, err := idx.Failures(ctx)
+	require.NoError(t, err, "expected no error")
+	require.Equal(t, []uint{1, 2}, failedNums(failures), "expected images larger than the limit to fail")
+	assert.Equal(t, xkcdindex.FailureImage, failures[0].Class, "expected image failure")
+	assert.Contains(t, failures[0].Error, xkcd.ErrImageTooLarge.Error(), "expected image to be too large")
+	meta, err := idx.GetImageMetadata(ctx, 1)
+	assert.NoError(t, err, "expected no error")
+	assert.Nil(t, meta, "expected image not to be stored")
+}
+
+func TestIndex_WithClient(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.index")
+	idx, err := xkcdindex.New(path, getLogger())
+	require.NoError(t, err)
+	require.NoError(t, idx.Init(ctx, false, false))
+	require.NoError(t, idx.Update(ctx, getTestClient(t), 1, 2, 2))
+	require.NoError(t, idx.Close())
+
+	client := xkcd.New(xkcd.WithClient(getTestHTTPClient(t)), xkcd.WithMaxImageBytes(16))
+	idx, err = xkcdindex.New(path, getLogger(), xkcdindex.WithClient(client))
+	require.NoError(t, err)
+	defer idx.Close()
+	post, err := idx.Get(ctx, client, 1)
+	require.NoError(t, err, "expected no error")
+	content, err := post.GetImageContent(ctx)
+	require.NoError(t, err, "expected no error")
+	defer content.Close()
+	_, err = io.ReadAll(content)
+	assert.ErrorIs(t, err, xkcd.ErrImageTooLarge, "expected limit of the client to apply to indexed posts")
+	var tooLarge *xkcd.ImageTooLargeError
+	require.ErrorAs(t, err, &tooLarge, "expected an image too large error")
+	assert.Equal(t, int64(16), tooLarge.MaxBytes, "expected limit of the client")
+}
+
+func TestIndex_WithClient_Mirrors(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.index")
+	idx, err := xkcdindex.New(path, getLogger())
+	require.NoError(t, err)
+	require.NoError(t, idx.Init(ctx, false, false))
+	require.NoError(t, idx.Update(ctx, getTestClient(t), 1, 2, 2))
+	require.NoError(t, idx.Close())
+
+	working := getTestHTTPClient(t)
+	var requested []string
+	client := xkcd.New(
+		xkcd.WithClient(&http.Client{Transport: mockRoundTripper(func(r *http.Request) (*http.Response, error) {
+			requested = append(requested, r.URL.Host)
+			switch r.URL.Host {
+			case "imgs.xkcd.com":
+				return &http.Response{
+					StatusCode: http.StatusServiceUnavailable,
+					Body:       io.NopCloser(strings.NewReader("503 Service Unavailable")),
+				}, nil
+			case "imgs.mirror.example.com":
+				r.URL.Host = "imgs.xkcd.com"
+			}
+			return working.Do(r)
+		})}),
+		xkcd.WithRetryPolicy(xkcd.RetryPolicy{
+			MaxAttempts:       2,
+			BaseDelay:         time.Millisecond,
+			MaxDelay:          time.Millisecond,
+			RetryableStatuses: []int{http.StatusServiceUnavailable},
+		}),
+		xkcd.WithMirrors(xkcd.Mirror{
+			BaseURL:      "https://api.mirror.example.com",
+			ImageBaseURL: "https://imgs.mirror.example.com",
+		}),
+	)
+	idx, err = xkcdindex.New(path, getLogger(), xkcdindex.WithClient(client))
+	require.NoError(t, err)
+	defer idx.Close()
+	post, err := idx.Get(ctx, client, 1)
+	require.NoError(t, err, "expected no error")
+	content, err := post.GetImageContent(ctx)
+	require.NoError(t, err, "expected image to be fetched from the mirror")
+	defer content.Close()
+	_, err = io.ReadAll(content)
+	require.NoError(t, err, "expected no error")
+	assert.Equal(
+		t,
+		[]string{"imgs.xkcd.com", "imgs.xkcd.com", "imgs.mirror.example.com"},
+		requested,
+		"expected image to be retried, then fetched from the mirror",
+	)
+}
+
+func TestIndex_WithClient_AllowedHosts(t *testing.T) {
+	ctx := context.Background()
+	working := getTestHTTPClient(t)
+	client := xkcd.New(
+		xkcd.WithClient(&http.Client{Transport: mockRoundTripper(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Host == "images.example.com" {
+				return getTestImageResponse(t, r.URL.Path)
+			}
+			resp, err := working.Do(r)
+			if err != nil {
+				return nil, err
+			}
+			record, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			resp.Body = io.NopCloser(strings.NewReader(strings.ReplaceAll(string(record), "imgs.xkcd.com", "images.example.com")))
+			return resp, nil
+		})}),
+		xkcd.WithAllowedHosts("xkcd.com", "example.com"),
+	)
+	path := filepath.Join(t.TempDir(), "test.index")
+	idx, err := xkcdindex.New(path, getLogger())
+	require.NoError(t, err)
+	require.NoError(t, idx.Init(ctx, false, false))
+	require.NoError(t, idx.Update(ctx, client, 1, 2, 2))
+	require.NoError(t, idx.Close())
+
+	t.Run("unbound posts", func(t *testing.T) {
+		idx, err := xkcdindex.New(path, getLogger(), xkcdindex.WithHTTPClient(client.HTTPClient()))
+		require.NoError(t, err)
+		defer idx.Close()
+		post, err := idx.Get(ctx, client, 1)
+		require.NoError(t, err, "expected no error")
+		_, err = post.GetImageContent(ctx)
+		assert.ErrorIs(t, err, xkcd.ErrHostNotAllowed, "expected image host not to be allowed by default")
+	})
+
+	t.Run("bound posts", func(t *testing.T) {
+		idx, err := xkcdindex.New(path, getLogger(), xkcdindex.WithClient(client))
+		require.NoError(t, err)
+		defer idx.Close()
+		post, err := idx.Get(ctx, client, 1)
+		require.NoError(t, err, "expected no error")
+		content, err := post.GetImageContent(ctx)
+		require.NoError(t, err, "expected image host to be allowed by the client")
+		assert.NoError(t, content.Close(), "expected no error while closing image")
+	})
+}
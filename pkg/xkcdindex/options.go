@@ -0,0 +1,45 @@
+package xkcdindex
+
+import "github.com/jucrouzet/xkcd/pkg/xkcd"
+
+// DefaultMaxImageBytes is the default maximum size of images stored in offline mode.
+const DefaultMaxImageBytes = int64(64 << 20)
+
+// Option is a function that configures an Index.
+type Option func(i *Index)
+
+// WithAutoMigrate sets whether pending schema migrations are applied when the index is opened.
+// It defaults to true, when disabled migrations can be applied with Index.Migrate.
+func WithAutoMigrate(enabled bool) Option {
+	return func(i *Index) {
+		i.autoMigrate = enabled
+	}
+}
+
+// WithHTTPClient sets the http client used to fetch images of posts that are not stored in index.
+// It defaults to the http client of the WithClient client, or else to a xkcd.NewHTTPClient client, refusing to
+// connect to loopback, private and link-local addresses, and to follow redirects to other hosts than
+// xkcd.DefaultAllowedHosts.
+func WithHTTPClient(c xkcd.HTTPClient) Option {
+	return func(i *Index) {
+		i.httpClient = c
+	}
+}
+
+// WithMaxImageBytes limits to n bytes the images stored in offline mode, whether they are fetched or imported.
+// Longer images fail with an *xkcd.ImageTooLargeError. It defaults to DefaultMaxImageBytes, and images are not
+// limited if n is not positive.
+func WithMaxImageBytes(n int64) Option {
+	return func(i *Index) {
+		i.maxImageBytes = n
+	}
+}
+
+// WithClient binds the posts read from the index to the xkcd API client c, as with xkcd.NewPostWithClient: the
+// mirrors, retry policy, image limits and allowed hosts of c apply to fetching their images.
+// Posts are not bound to a client by default, and their images must be on xkcd.DefaultAllowedHosts.
+func WithClient(c *xkcd.Client) Option {
+	return func(i *Index) {
+		i.apiClient = c
+	}
+}
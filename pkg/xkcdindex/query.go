@@ -0,0 +1,517 @@
+package xkcdindex
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// QueryError is returned when a search query cannot be parsed.
+type QueryError struct {
+	// Query is the query that failed to parse.
+	Query string
+	// Pos is the byte offset in Query where the error was found.
+	Pos int
+	// Message describes the error.
+	Message string
+}
+
+// Error implements the error interface.
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid query at position %d: %s", e.Column()+1, e.Message)
+}
+
+// Column returns the position in Query where the error was found in characters, rather than in bytes as Pos,
+// to point at it when displaying the query.
+func (e *QueryError) Column() int {
+	if e.Pos < 0 || e.Pos > len(e.Query) {
+		return e.Pos
+	}
+	return utf8.RuneCountInString(e.Query[:e.Pos])
+}
+
+// textFields maps query field names to the full-text searchable posts columns.
+var textFields = map[string]string{
+	"title":      "title",
+	"alt":        "alt_text",
+	"transcript": "transcript",
+	"news":       "news",
+}
+
+// rangeFields are query fields matching a range of values, rather than text.
+var rangeFields = map[string]bool{
+	"after":  true,
+	"before": true,
+	"date":   true,
+	"num":    true,
+	"year":   true,
+}
+
+type tokenKind int
+
+const (
+	tokenTerm tokenKind = iota
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+	tokenEOF
+)
+
+type token struct {
+	kind   tokenKind
+	pos    int
+	field  string
+	value  string
+	quoted bool
+}
+
+type lexer struct {
+	query string
+	pos   int
+}
+
+func (l *lexer) errorf(pos int, format string, args ...any) error {
+	return &QueryError{
+		Query:   l.query,
+		Pos:     pos,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.query) {
+		r, size := utf8.DecodeRuneInString(l.query[l.pos:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		l.pos += size
+	}
+	start := l.pos
+	if l.pos >= len(l.query) {
+		return token{kind: tokenEOF, pos: start}, nil
+	}
+	switch l.query[l.pos] {
+	case '(':
+		l.pos++
+		return token{kind: tokenLParen, pos: start}, nil
+	case ')':
+		l.pos++
+		return token{kind: tokenRParen, pos: start}, nil
+	case '"':
+		value, err := l.quoted()
+		if err != nil {
+			return token{}, err
+		}
+		return token{kind: tokenTerm, pos: start, value: value, quoted: true}, nil
+	case '-':
+		if l.pos+1 < len(l.query) && !l.isDelimiter(l.pos+1) {
+			l.pos++
+			return token{kind: tokenNot, pos: start}, nil
+		}
+	}
+	for l.pos < len(l.query) && !l.isDelimiter(l.pos) {
+		_, size := utf8.DecodeRuneInString(l.query[l.pos:])
+		l.pos += size
+	}
+	word := l.query[start:l.pos]
+	switch word {
+	case "AND":
+		return token{kind: tokenAnd, pos: start}, nil
+	case "OR":
+		return token{kind: tokenOr, pos: start}, nil
+	case "NOT":
+		return token{kind: tokenNot, pos: start}, nil
+	}
+	field, value, found := strings.Cut(word, ":")
+	if !found {
+		return token{kind: tokenTerm, pos: start, value: word}, nil
+	}
+	field = strings.ToLower(field)
+	if _, ok := textFields[field]; !ok && !rangeFields[field] {
+		return token{}, l.errorf(start, "unknown field %q", field)
+	}
+	tok := token{kind: tokenTerm, pos: start, field: field, value: value}
+	if value == "" && l.pos < len(l.query) && l.query[l.pos] == '"' {
+		var err error
+		if tok.value, err = l.quoted(); err != nil {
+			return token{}, err
+		}
+		tok.quoted = true
+	}
+	if tok.value == "" && !tok.quoted {
+		return token{}, l.errorf(l.pos, "missing value for field %q", field)
+	}
+	return tok, nil
+}
+
+func (l *lexer) isDelimiter(pos int) bool {
+	r, _ := utf8.DecodeRuneInString(l.query[pos:])
+	return unicode.IsSpace(r) || r == '(' || r == ')' || r == '"'
+}
+
+// quoted reads a quoted string starting at the current position.
+func (l *lexer) quoted() (string, error) {
+	start := l.pos
+	end := strings.IndexByte(l.query[start+1:], '"')
+	if end < 0 {
+		return "", l.errorf(start, "unterminated quoted string")
+	}
+	l.pos = start + end + 2
+	return l.query[start+1 : start+1+end], nil
+}
+
+// queryNode is a node of a parsed search query.
+type queryNode interface {
+	// build appends the SQL condition of the node to the builder.
+	build(b *queryBuilder)
+}
+
+type andNode struct {
+	left, right queryNode
+}
+
+type orNode struct {
+	left, right queryNode
+}
+
+type notNode struct {
+	node queryNode
+}
+
+// textNode matches posts with a word or a phrase in one or all of the full-text searchable columns.
+type textNode struct {
+	column string
+	value  string
+	prefix bool
+}
+
+// rangeNode matches posts with a column value in [low..high[, a nil bound being unbounded.
+type rangeNode struct {
+	column    string
+	low, high *int64
+}
+
+type parser struct {
+	lexer   *lexer
+	current token
+}
+
+// parseQuery parses a search query, see Index.Search for its syntax.
+func parseQuery(query string) (queryNode, error) {
+	p := &parser{lexer: &lexer{query: query}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.current.kind == tokenEOF {
+		return nil, p.lexer.errorf(0, "query is empty")
+	}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.current.kind != tokenEOF {
+		return nil, p.lexer.errorf(p.current.pos, "unexpected %s", p.describe())
+	}
+	return node, nil
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.current = tok
+	return nil
+}
+
+func (p *parser) describe() string {
+	switch p.current.kind {
+	case tokenAnd:
+		return "AND"
+	case tokenOr:
+		return "OR"
+	case tokenNot:
+		return "NOT"
+	case tokenLParen:
+		return "("
+	case tokenRParen:
+		return ")"
+	case tokenEOF:
+		return "end of query"
+	}
+	return fmt.Sprintf("term %q", p.current.value)
+}
+
+func (p *parser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.current.kind == tokenOr {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (queryNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.current.kind {
+		case tokenAnd:
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		case tokenTerm, tokenNot, tokenLParen:
+		default:
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (queryNode, error) {
+	switch p.current.kind {
+	case tokenNot:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{node: node}, nil
+	case tokenLParen:
+		open := p.current.pos
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.current.kind != tokenRParen {
+			return nil, p.lexer.errorf(open, "unclosed parenthesis")
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case tokenTerm:
+		node, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+	return nil, p.lexer.errorf(p.current.pos, "unexpected %s", p.describe())
+}
+
+func (p *parser) parseTerm() (queryNode, error) {
+	tok := p.current
+	if rangeFields[tok.field] {
+		return p.parseRange(tok)
+	}
+	value := tok.value
+	prefix := false
+	if !tok.quoted && strings.HasSuffix(value, "*") {
+		value = strings.TrimSuffix(value, "*")
+		prefix = true
+	}
+	if strings.TrimSpace(value) == "" {
+		return nil, p.lexer.errorf(tok.pos, "empty search term")
+	}
+	return &textNode{
+		column: textFields[tok.field],
+		value:  value,
+		prefix: prefix,
+	}, nil
+}
+
+// parseRange parses the value of a range field into the [low..high[ range it matches.
+func (p *parser) parseRange(tok token) (queryNode, error) {
+	column := "date"
+	if tok.field == "num" {
+		column = "num"
+	}
+	value := tok.value
+	var low, high *int64
+	var err error
+	switch {
+	case tok.field == "before" || tok.field == "after":
+		var start, end int64
+		if start, end, err = parseBound(tok.field, value); err != nil {
+			return nil, p.lexer.errorf(tok.pos, "%s", err.Error())
+		}
+		if tok.field == "before" {
+			high = &start
+		} else {
+			low = &end
+		}
+	case strings.Contains(value, ".."):
+		from, to, _ := strings.Cut(value, "..")
+		if from == "" && to == "" {
+			return nil, p.lexer.errorf(tok.pos, "range of field %q has no bounds", tok.field)
+		}
+		if from != "" {
+			var start int64
+			if start, _, err = parseBound(tok.field, from); err != nil {
+				return nil, p.lexer.errorf(tok.pos, "%s", err.Error())
+			}
+			low = &start
+		}
+		if to != "" {
+			var end int64
+			if _, end, err = parseBound(tok.field, to); err != nil {
+				return nil, p.lexer.errorf(tok.pos, "%s", err.Error())
+			}
+			high = &end
+		}
+	default:
+		operator := strings.TrimRightFunc(value, func(r rune) bool { return r != '<' && r != '>' && r != '=' })
+		var start, end int64
+		if start, end, err = parseBound(tok.field, value[len(operator):]); err != nil {
+			return nil, p.lexer.errorf(tok.pos, "%s", err.Error())
+		}
+		switch operator {
+		case "":
+			low, high = &start, &end
+		case ">":
+			low = &end
+		case ">=":
+			low = &start
+		case "<":
+			high = &start
+		case "<=":
+			high = &end
+		default:
+			return nil, p.lexer.errorf(tok.pos, "invalid comparison operator %q", operator)
+		}
+	}
+	if low != nil && high != nil && *low >= *high {
+		return nil, p.lexer.errorf(tok.pos, "range of field %q is empty", tok.field)
+	}
+	return &rangeNode{column: column, low: low, high: high}, nil
+}
+
+// parseBound parses a single value of a range field into the [start..end[ range it covers.
+func parseBound(field, value string) (int64, int64, error) {
+	switch field {
+	case "num":
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid post number %q", value)
+		}
+		return int64(n), int64(n) + 1, nil
+	case "year":
+		y, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid year %q", value)
+		}
+		start := time.Date(y, time.January, 1, 0, 0, 0, 0, time.Local)
+		return start.Unix(), start.AddDate(1, 0, 0).Unix(), nil
+	}
+	for _, layout := range []struct {
+		format              string
+		years, months, days int
+	}{
+		{format: time.DateOnly, days: 1},
+		{format: "2006-01", months: 1},
+		{format: "2006", years: 1},
+	} {
+		start, err := time.ParseInLocation(layout.format, value, time.Local)
+		if err == nil {
+			return start.Unix(), start.AddDate(layout.years, layout.months, layout.days).Unix(), nil
+		}
+	}
+	return 0, 0, fmt.Errorf("invalid date %q, expected YYYY, YYYY-MM or YYYY-MM-DD", value)
+}
+
+// queryBuilder translates a parsed query into a SQL condition on the posts table.
+type queryBuilder struct {
+	sql     strings.Builder
+	args    []any
+	negated bool
+	// ranking are the full-text expressions of the non negated text terms, used to rank results.
+	ranking []string
+}
+
+// translateQuery translates a parsed query into a SQL condition on the posts table, its arguments, and a
+// full-text query matching the posts to rank, which is empty if the query has no text term to rank on.
+func translateQuery(node queryNode) (string, []any, string) {
+	b := &queryBuilder{}
+	node.build(b)
+	return b.sql.String(), b.args, strings.Join(b.ranking, " OR ")
+}
+
+func (n *andNode) build(b *queryBuilder) {
+	b.sql.WriteString("(")
+	n.left.build(b)
+	b.sql.WriteString(" AND ")
+	n.right.build(b)
+	b.sql.WriteString(")")
+}
+
+func (n *orNode) build(b *queryBuilder) {
+	b.sql.WriteString("(")
+	n.left.build(b)
+	b.sql.WriteString(" OR ")
+	n.right.build(b)
+	b.sql.WriteString(")")
+}
+
+func (n *notNode) build(b *queryBuilder) {
+	b.negated = !b.negated
+	b.sql.WriteString("NOT ")
+	n.node.build(b)
+	b.negated = !b.negated
+}
+
+func (n *textNode) build(b *queryBuilder) {
+	match := `"` + strings.ReplaceAll(n.value, `"`, `""`) + `"`
+	if n.prefix {
+		match += "*"
+	}
+	if n.column != "" {
+		match = n.column + " : " + match
+	}
+	b.sql.WriteString("posts.num IN (SELECT rowid FROM posts_fts WHERE posts_fts MATCH ?)")
+	b.args = append(b.args, match)
+	if !b.negated {
+		b.ranking = append(b.ranking, match)
+	}
+}
+
+func (n *rangeNode) build(b *queryBuilder) {
+	var conditions []string
+	if n.low != nil {
+		conditions = append(conditions, "posts."+n.column+" >= ?")
+		b.args = append(b.args, *n.low)
+	}
+	if n.high != nil {
+		conditions = append(conditions, "posts."+n.column+" < ?")
+		b.args = append(b.args, *n.high)
+	}
+	b.sql.WriteString("(" + strings.Join(conditions, " AND ") + ")")
+}
@@ -0,0 +1,237 @@
+package xkcdindex_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+// ftsMatch is the SQL condition of a full-text search term.
+const ftsMatch = "posts.num IN (SELECT rowid FROM posts_fts WHERE posts_fts MATCH ?)"
+
+func unixDate(year int, month time.Month, day int) int64 {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local).Unix()
+}
+
+func TestTranslateQuery(t *testing.T) {
+	tests := []struct {
+		query     string
+		condition string
+		args      []any
+		ranking   string
+	}{
+		{
+			query:     "island",
+			condition: ftsMatch,
+			args:      []any{`"island"`},
+			ranking:   `"island"`,
+		},
+		{
+			query:     "isl*",
+			condition: ftsMatch,
+			args:      []any{`"isl"*`},
+			ranking:   `"isl"*`,
+		},
+		{
+			query:     `"petit trees*"`,
+			condition: ftsMatch,
+			args:      []any{`"petit trees*"`},
+			ranking:   `"petit trees*"`,
+		},
+		{
+			query:     "TITLE:Barrel",
+			condition: ftsMatch,
+			args:      []any{`title : "Barrel"`},
+			ranking:   `title : "Barrel"`,
+		},
+		{
+			query:     `alt:"post 7"`,
+			condition: ftsMatch,
+			args:      []any{`alt_text : "post 7"`},
+			ranking:   `alt_text : "post 7"`,
+		},
+		{
+			query:     "voilà Å",
+			condition: "(" + ftsMatch + " AND " + ftsMatch + ")",
+			args:      []any{`"voilà"`, `"Å"`},
+			ranking:   `"voilà" OR "Å"`,
+		},
+		{
+			query:     "title:Ålesund\u00a0café",
+			condition: "(" + ftsMatch + " AND " + ftsMatch + ")",
+			args:      []any{`title : "Ålesund"`, `"café"`},
+			ranking:   `title : "Ålesund" OR "café"`,
+		},
+		{
+			query:     "island barrel",
+			condition: "(" + ftsMatch + " AND " + ftsMatch + ")",
+			args:      []any{`"island"`, `"barrel"`},
+			ranking:   `"island" OR "barrel"`,
+		},
+		{
+			query:     "island AND barrel",
+			condition: "(" + ftsMatch + " AND " + ftsMatch + ")",
+			args:      []any{`"island"`, `"barrel"`},
+			ranking:   `"island" OR "barrel"`,
+		},
+		{
+			query:     "island OR barrel tree",
+			condition: "(" + ftsMatch + " OR (" + ftsMatch + " AND " + ftsMatch + "))",
+			args:      []any{`"island"`, `"barrel"`, `"tree"`},
+			ranking:   `"island" OR "barrel" OR "tree"`,
+		},
+		{
+			query:     "(island OR barrel) tree",
+			condition: "((" + ftsMatch + " OR " + ftsMatch + ") AND " + ftsMatch + ")",
+			args:      []any{`"island"`, `"barrel"`, `"tree"`},
+			ranking:   `"island" OR "barrel" OR "tree"`,
+		},
+		{
+			query:     "island -barrel",
+			condition: "(" + ftsMatch + " AND NOT " + ftsMatch + ")",
+			args:      []any{`"island"`, `"barrel"`},
+			ranking:   `"island"`,
+		},
+		{
+			query:     "NOT (island OR barrel)",
+			condition: "NOT (" + ftsMatch + " OR " + ftsMatch + ")",
+			args:      []any{`"island"`, `"barrel"`},
+			ranking:   "",
+		},
+		{
+			query:     "NOT -island",
+			condition: "NOT NOT " + ftsMatch,
+			args:      []any{`"island"`},
+			ranking:   `"island"`,
+		},
+		{
+			query:     "num:14",
+			condition: "(posts.num >= ? AND posts.num < ?)",
+			args:      []any{int64(14), int64(15)},
+		},
+		{
+			query:     "num:>28",
+			condition: "(posts.num >= ?)",
+			args:      []any{int64(29)},
+		},
+		{
+			query:     "num:>=28",
+			condition: "(posts.num >= ?)",
+			args:      []any{int64(28)},
+		},
+		{
+			query:     "num:<3",
+			condition: "(posts.num < ?)",
+			args:      []any{int64(3)},
+		},
+		{
+			query:     "num:<=3",
+			condition: "(posts.num < ?)",
+			args:      []any{int64(4)},
+		},
+		{
+			query:     "num:..2",
+			condition: "(posts.num < ?)",
+			args:      []any{int64(3)},
+		},
+		{
+			query:     "num:10..",
+			condition: "(posts.num >= ?)",
+			args:      []any{int64(10)},
+		},
+		{
+			query:     "year:2007",
+			condition: "(posts.date >= ? AND posts.date < ?)",
+			args:      []any{unixDate(2007, time.January, 1), unixDate(2008, time.January, 1)},
+		},
+		{
+			query:     "date:2007-02-01..2007-03",
+			condition: "(posts.date >= ? AND posts.date < ?)",
+			args:      []any{unixDate(2007, time.February, 1), unixDate(2007, time.April, 1)},
+		},
+		{
+			query:     "before:2006-03",
+			condition: "(posts.date < ?)",
+			args:      []any{unixDate(2006, time.March, 1)},
+		},
+		{
+			query:     "after:2008-09",
+			condition: "(posts.date >= ?)",
+			args:      []any{unixDate(2008, time.October, 1)},
+		},
+		{
+			query:     "transcript:wonder num:>25",
+			condition: "(" + ftsMatch + " AND (posts.num >= ?))",
+			args:      []any{`transcript : "wonder"`, int64(26)},
+			ranking:   `transcript : "wonder"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			condition, args, ranking, err := xkcdindex.TranslateQuery(tt.query)
+			require.NoError(t, err, "expected no error")
+			assert.Equal(t, tt.condition, condition, "expected SQL condition")
+			assert.Equal(t, tt.args, args, "expected SQL arguments")
+			assert.Equal(t, tt.ranking, ranking, "expected ranking query")
+		})
+	}
+}
+
+func TestTranslateQuery_Errors(t *testing.T) {
+	tests := []struct {
+		query   string
+		pos     int
+		message string
+	}{
+		{query: "", pos: 0, message: "query is empty"},
+		{query: "   ", pos: 0, message: "query is empty"},
+		{query: "island (title:barrel", pos: 7, message: "unclosed parenthesis"},
+		{query: "(island (barrel)", pos: 0, message: "unclosed parenthesis"},
+		{query: "island)", pos: 6, message: "unexpected )"},
+		{query: "  )", pos: 2, message: "unexpected )"},
+		{query: "()", pos: 1, message: "unexpected )"},
+		{query: "island AND AND barrel", pos: 11, message: "unexpected AND"},
+		{query: "OR island", pos: 0, message: "unexpected OR"},
+		{query: "island OR", pos: 9, message: "unexpected end of query"},
+		{query: "island NOT", pos: 10, message: "unexpected end of query"},
+		{query: "author:randall", pos: 0, message: `unknown field "author"`},
+		{query: "barrel Author:randall", pos: 7, message: `unknown field "author"`},
+		{query: "barrel year:", pos: 12, message: `missing value for field "year"`},
+		{query: `alt:"unterminated`, pos: 4, message: "unterminated quoted string"},
+		{query: `island "unterminated`, pos: 7, message: "unterminated quoted string"},
+		{query: "title:*", pos: 0, message: "empty search term"},
+		{query: `island "  "`, pos: 7, message: "empty search term"},
+		{query: "num:..", pos: 0, message: `range of field "num" has no bounds`},
+		{query: "num:20..10", pos: 0, message: `range of field "num" is empty`},
+		{query: "num:=>10", pos: 0, message: `invalid comparison operator "=>"`},
+		{query: "num:ten", pos: 0, message: `invalid post number "ten"`},
+		{query: "year:twenty", pos: 0, message: `invalid year "twenty"`},
+		{query: "x before:2010-13", pos: 2, message: `invalid date "2010-13", expected YYYY, YYYY-MM or YYYY-MM-DD`},
+		{query: "x date:2010..2010-1", pos: 2, message: `invalid date "2010-1", expected YYYY, YYYY-MM or YYYY-MM-DD`},
+		// Positions are byte offsets.
+		{query: "café )", pos: 6, message: "unexpected )"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, _, _, err := xkcdindex.TranslateQuery(tt.query)
+			var queryErr *xkcdindex.QueryError
+			require.ErrorAs(t, err, &queryErr, "expected a QueryError")
+			assert.Equal(t, tt.query, queryErr.Query, "expected error to hold the query")
+			assert.Equal(t, tt.pos, queryErr.Pos, "expected error to point at the error position")
+			assert.Equal(t, tt.message, queryErr.Message, "expected error to have correct message")
+		})
+	}
+}
+
+func TestQueryError_Error(t *testing.T) {
+	err := &xkcdindex.QueryError{Query: "island)", Pos: 6, Message: "unexpected )"}
+	assert.Equal(t, "invalid query at position 7: unexpected )", err.Error(), "expected positions to start at 1")
+
+	err = &xkcdindex.QueryError{Query: "café )", Pos: 6, Message: "unexpected )"}
+	assert.Equal(t, 5, err.Column(), "expected column to count characters, not bytes")
+	assert.Equal(t, "invalid query at position 6: unexpected )", err.Error(), "expected positions to count characters")
+}
@@ -0,0 +1,128 @@
+package xkcdindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+// Stats are statistics about the index content.
+type Stats struct {
+	// Posts is the number of indexed posts.
+	Posts uint `json:"posts"`
+	// FirstNum is the number of the first indexed post.
+	FirstNum uint `json:"first_num"`
+	// LastNum is the number of the last indexed post.
+	LastNum uint `json:"last_num"`
+	// Gaps are the numbers of posts up to the last updated one which are not indexed,
+	// except known absences like comic #404.
+	Gaps []uint `json:"gaps"`
+	// WithTranscript is the number of indexed posts with a transcript.
+	WithTranscript uint `json:"with_transcript"`
+	// WithoutTranscript is the number of indexed posts without transcript.
+	WithoutTranscript uint `json:"without_transcript"`
+	// Offline is true if the index is in offline mode.
+	Offline bool `json:"offline"`
+	// OfflineImages is the number of stored images.
+	OfflineImages uint `json:"offline_images"`
+	// OfflineImagesSize is the total size of stored images in bytes.
+	OfflineImagesSize int64 `json:"offline_images_size"`
+	// FileSize is the size of the index file in bytes.
+	FileSize int64 `json:"file_size"`
+	// LastUpdate is the date of the last update, nil if the index was never updated.
+	LastUpdate *time.Time `json:"last_update"`
+	// PostsPerYear is the number of indexed posts published each year, ordered by year.
+	PostsPerYear []YearStats `json:"posts_per_year"`
+}
+
+// YearStats is the number of posts published a given year.
+type YearStats struct {
+	// Year is the publication year.
+	Year int `json:"year"`
+	// Posts is the number of indexed posts published during the year.
+	Posts uint `json:"posts"`
+}
+
+// Stats returns statistics about the index content.
+func (i *Index) Stats(ctx context.Context) (*Stats, error) {
+	if i.db == nil {
+		return nil, errors.New("index is not initialized")
+	}
+	stats := &Stats{Offline: i.offline, Gaps: []uint{}, PostsPerYear: []YearStats{}}
+	info, err := os.Stat(i.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index file size: %w", err)
+	}
+	stats.FileSize = info.Size()
+	lastDate, lastNum, err := i.GetLastUpdate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last update: %w", err)
+	}
+	if lastDate.Unix() > 0 {
+		stats.LastUpdate = &lastDate
+	}
+	known, err := i.knownAbsences(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := i.db.QueryContext(
+		ctx,
+		"SELECT num, date, transcript != '', content IS NOT NULL, coalesce(length(content), 0) FROM posts ORDER BY num",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read posts: %w", err)
+	}
+	defer rows.Close()
+	years := map[int]uint{}
+	next := uint(1)
+	for rows.Next() {
+		var num uint
+		var ts, size int64
+		var hasTranscript, hasContent bool
+		if err := rows.Scan(&num, &ts, &hasTranscript, &hasContent, &size); err != nil {
+			return nil, fmt.Errorf("failed to read posts: %w", err)
+		}
+		if stats.Posts == 0 {
+			stats.FirstNum = num
+		}
+		stats.Posts++
+		stats.LastNum = num
+		stats.Gaps = appendGaps(stats.Gaps, next, num, known)
+		next = num + 1
+		if hasTranscript {
+			stats.WithTranscript++
+		} else {
+			stats.WithoutTranscript++
+		}
+		if hasContent {
+			stats.OfflineImages++
+			stats.OfflineImagesSize += size
+		}
+		years[time.Unix(ts, 0).Year()]++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read posts: %w", err)
+	}
+	stats.Gaps = appendGaps(stats.Gaps, next, lastNum+1, known)
+	for year, posts := range years {
+		stats.PostsPerYear = append(stats.PostsPerYear, YearStats{Year: year, Posts: posts})
+	}
+	slices.SortFunc(stats.PostsPerYear, func(a, b YearStats) int {
+		return a.Year - b.Year
+	})
+	return stats, nil
+}
+
+// appendGaps appends to gaps the numbers in range [from..to[ which are not known absences.
+func appendGaps(gaps []uint, from, to uint, known map[uint]bool) []uint {
+	for num := from; num < to; num++ {
+		if !known[num] {
+			gaps = append(gaps, num)
+		}
+	}
+	return gaps
+}
@@ -0,0 +1,48 @@
+package xkcdindex_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+func TestIndex_Stats(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("empty index", func(t *testing.T) {
+		stats, err := getEmptyIndex(t, false).Stats(ctx)
+		require.NoError(t, err, "expected no error")
+		assert.Zero(t, stats.Posts, "expected no post")
+		assert.Empty(t, stats.Gaps, "expected no gap")
+		assert.Empty(t, stats.PostsPerYear, "expected no year")
+		assert.Nil(t, stats.LastUpdate, "expected index never to be updated")
+		assert.Positive(t, stats.FileSize, "expected index file size")
+	})
+
+	t.Run("offline index", func(t *testing.T) {
+		idx := getTestIndexWithMode(t, true)
+		_, err := idx.DB().ExecContext(ctx, "DELETE FROM posts WHERE num IN (1, 12)")
+		require.NoError(t, err)
+		stats, err := idx.Stats(ctx)
+		require.NoError(t, err, "expected no error")
+		assert.Equal(t, uint(testPostsCount-2), stats.Posts, "expected indexed posts count")
+		assert.Equal(t, uint(2), stats.FirstNum, "expected first post number")
+		assert.Equal(t, uint(testPostsCount), stats.LastNum, "expected last post number")
+		assert.Equal(t, []uint{1, 12}, stats.Gaps, "expected gaps")
+		assert.Equal(t, uint(14), stats.WithTranscript, "expected odd posts to have a transcript")
+		assert.Equal(t, uint(14), stats.WithoutTranscript, "expected even posts not to have a transcript")
+		assert.True(t, stats.Offline, "expected offline index")
+		assert.Equal(t, uint(testPostsCount-2), stats.OfflineImages, "expected images to be stored")
+		assert.Positive(t, stats.OfflineImagesSize, "expected images size")
+		assert.NotNil(t, stats.LastUpdate, "expected last update date")
+		assert.Equal(t, []xkcdindex.YearStats{
+			{Year: 2006, Posts: 9},
+			{Year: 2007, Posts: 9},
+			{Year: 2008, Posts: 10},
+		}, stats.PostsPerYear, "expected posts per year")
+	})
+}
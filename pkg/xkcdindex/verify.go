@@ -0,0 +1,223 @@
+package xkcdindex
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcd"
+)
+
+// ProblemKind is a kind of problem found by Index.Verify.
+type ProblemKind string
+
+const (
+	// ProblemIntegrity is an SQLite integrity check error, it cannot be repaired.
+	ProblemIntegrity ProblemKind = "integrity"
+	// ProblemMissingPost is a post which is not indexed, while later posts are.
+	ProblemMissingPost ProblemKind = "missing_post"
+	// ProblemInvalidPost is an indexed post with an invalid date or URL.
+	ProblemInvalidPost ProblemKind = "invalid_post"
+	// ProblemMissingImage is a post of an offline index without stored image.
+	ProblemMissingImage ProblemKind = "missing_image"
+	// ProblemInvalidImage is a post of an offline index with a stored image that cannot be decoded.
+	ProblemInvalidImage ProblemKind = "invalid_image"
+)
+
+// Problem is a problem found by Index.Verify.
+type Problem struct {
+	// Kind is the kind of problem.
+	Kind ProblemKind `json:"kind"`
+	// Num is the number of the post with the problem, zero for integrity problems.
+	Num uint `json:"num,omitempty"`
+	// Message describes the problem.
+	Message string `json:"message"`
+}
+
+// Verify checks the index database integrity, that all posts up to the last indexed one are indexed,
+// except known absences like comic #404, that indexed posts are valid, and that their images are stored
+// and can be decoded if the index is in offline mode.
+// It returns the problems found, integrity problems first, then ordered by post number.
+func (i *Index) Verify(ctx context.Context) ([]Problem, error) {
+	if i.db == nil {
+		return nil, errors.New("index is not initialized")
+	}
+	problems, err := i.verifyIntegrity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	known, err := i.knownAbsences(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, lastNum, err := i.GetLastUpdate()
+	if err != nil {
+		return nil, err
+	}
+
+	next := uint(1)
+	missing := func(upTo uint) {
+		for _, num := range appendGaps(nil, next, upTo, known) {
+			problems = append(problems, Problem{Kind: ProblemMissingPost, Num: num, Message: "post is not indexed"})
+		}
+	}
+	err = i.eachPost(ctx, i.offline, func(post *xkcd.Post, content []byte) error {
+		missing(post.Num)
+		next = post.Num + 1
+		problems = append(problems, i.verifyPost(post, content)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	missing(lastNum + 1)
+	i.logger.Debug("verified index", slog.Int("problems", len(problems)))
+	return problems, nil
+}
+
+func (i *Index) verifyIntegrity(ctx context.Context) ([]Problem, error) {
+	rows, err := i.db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check index integrity: %w", err)
+	}
+	defer rows.Close()
+	var problems []Problem
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return nil, fmt.Errorf("failed to check index integrity: %w", err)
+		}
+		if msg != "ok" {
+			problems = append(problems, Problem{Kind: ProblemIntegrity, Message: msg})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to check index integrity: %w", err)
+	}
+	return problems, nil
+}
+
+func (i *Index) knownAbsences(ctx context.Context) (map[uint]bool, error) {
+	rows, err := i.db.QueryContext(ctx, "SELECT num FROM known_absences")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read known absences: %w", err)
+	}
+	defer rows.Close()
+	known := map[uint]bool{}
+	for rows.Next() {
+		var num uint
+		if err := rows.Scan(&num); err != nil {
+			return nil, fmt.Errorf("failed to read known absences: %w", err)
+		}
+		known[num] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read known absences: %w", err)
+	}
+	return known, nil
+}
+
+// minPostDate is the earliest valid post date, xkcd was started in 2005.
+var minPostDate = time.Date(2005, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// verifyPost returns the problems of an indexed post, and its stored image content.
+func (i *Index) verifyPost(post *xkcd.Post, content []byte) []Problem {
+	var problems []Problem
+	if post.Link == "" {
+		problems = append(problems, Problem{Kind: ProblemInvalidPost, Num: post.Num, Message: "post link URL is empty"})
+	} else if err := post.Validate(); err != nil {
+		problems = append(problems, Problem{Kind: ProblemInvalidPost, Num: post.Num, Message: err.Error()})
+	}
+	// Day, Month and Year of indexed posts are set from their stored date, which Validate cannot catch.
+	if post.Date.Before(minPostDate) || post.Date.After(time.Now().AddDate(0, 0, 1)) {
+		problems = append(problems, Problem{
+			Kind:    ProblemInvalidPost,
+			Num:     post.Num,
+			Message: fmt.Sprintf("post date %s is invalid", post.Date.Format(time.DateOnly)),
+		})
+	}
+	if !i.offline {
+		return problems
+	}
+	if content == nil {
+		return append(problems, Problem{Kind: ProblemMissingImage, Num: post.Num, Message: "image is not stored"})
+	}
+	if _, _, err := image.Decode(bytes.NewReader(content)); err != nil {
+		problems = append(problems, Problem{
+			Kind:    ProblemInvalidImage,
+			Num:     post.Num,
+			Message: fmt.Sprintf("stored image cannot be decoded: %s", err),
+		})
+	}
+	return problems
+}
+
+// Repair fetches again the posts with problems returned by Verify, with their images if the index is
+// in offline mode, and returns the problems that were fixed.
+// Missing posts which do not exist are recorded as known absences. Integrity problems cannot be repaired.
+// If some posts could not be repaired, it returns the problems that were fixed, and an error.
+func (i *Index) Repair(ctx context.Context, client *xkcd.Client, problems []Problem) ([]Problem, error) {
+	if i.db == nil {
+		return nil, errors.New("index is not initialized")
+	}
+	byNum := map[uint][]Problem{}
+	var nums []uint
+	for _, p := range problems {
+		if p.Num == 0 {
+			continue
+		}
+		if _, ok := byNum[p.Num]; !ok {
+			nums = append(nums, p.Num)
+		}
+		byNum[p.Num] = append(byNum[p.Num], p)
+	}
+	slices.Sort(nums)
+
+	var fixed []Problem
+	failed := 0
+	for _, num := range nums {
+		log := i.logger.With(slog.Uint64("num", uint64(num)))
+		if err := i.repairPost(ctx, client, num, byNum[num], log); err != nil {
+			log.Warn("failed to repair post", slog.String("error", err.Error()))
+			failed++
+			continue
+		}
+		log.Debug("repaired post")
+		fixed = append(fixed, byNum[num]...)
+	}
+	if failed > 0 {
+		return fixed, fmt.Errorf("failed to repair %d posts", failed)
+	}
+	return fixed, nil
+}
+
+func (i *Index) repairPost(ctx context.Context, client *xkcd.Client, num uint, problems []Problem, log *slog.Logger) error {
+	post, err := client.GetPost(ctx, num)
+	if errors.Is(err, xkcd.ErrNoSuchPost) && len(problems) == 1 && problems[0].Kind == ProblemMissingPost {
+		log.Debug("recording known absence")
+		_, err = i.db.ExecContext(ctx, "INSERT OR IGNORE INTO known_absences (num, reason) VALUES (?, 'post does not exist')", num)
+		if err != nil {
+			return fmt.Errorf("failed to record known absence: %w", err)
+		}
+		return clearFailure(ctx, i.db, num)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch post from API: %w", err)
+	}
+	var data []byte
+	if i.offline {
+		data, err = i.readImageContent(ctx, post)
+		if err != nil {
+			return err
+		}
+	}
+	if err := upsertPost(ctx, post, data, i.db, log); err != nil {
+		return err
+	}
+	return clearFailure(ctx, i.db, num)
+}
@@ -0,0 +1,93 @@
+package xkcdindex_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jucrouzet/xkcd/pkg/xkcdindex"
+)
+
+func problemNums(problems []xkcdindex.Problem, kind xkcdindex.ProblemKind) []uint {
+	var nums []uint
+	for _, p := range problems {
+		if p.Kind == kind {
+			nums = append(nums, p.Num)
+		}
+	}
+	return nums
+}
+
+func TestIndex_Verify(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("valid index", func(t *testing.T) {
+		idx := getTestIndexWithMode(t, true)
+		problems, err := idx.Verify(ctx)
+		assert.NoError(t, err, "expected no error")
+		assert.Empty(t, problems, "expected no problem")
+	})
+
+	t.Run("known absences", func(t *testing.T) {
+		idx := getTestIndex(t)
+		_, err := idx.DB().ExecContext(ctx, "UPDATE last_update SET last_num = 405")
+		require.NoError(t, err)
+		problems, err := idx.Verify(ctx)
+		assert.NoError(t, err, "expected no error")
+		nums := problemNums(problems, xkcdindex.ProblemMissingPost)
+		assert.Len(t, nums, 405-testPostsCount-1, "expected posts after the last indexed one to be missing")
+		assert.NotContains(t, nums, uint(404), "expected comic #404 not to be reported")
+	})
+
+	t.Run("invalid dates", func(t *testing.T) {
+		idx := getTestIndex(t)
+		for num, date := range map[uint]time.Time{
+			2: time.Date(2004, time.December, 31, 0, 0, 0, 0, time.Local),
+			3: time.Now().AddDate(0, 1, 0),
+		} {
+			_, err := idx.DB().ExecContext(ctx, "UPDATE posts SET date = ? WHERE num = ?", date.Unix(), num)
+			require.NoError(t, err)
+		}
+		problems, err := idx.Verify(ctx)
+		assert.NoError(t, err, "expected no error")
+		require.Equal(t, []uint{2, 3}, problemNums(problems, xkcdindex.ProblemInvalidPost), "expected posts before 2005 or in the future to be invalid")
+		assert.Contains(t, problems[0].Message, "post date 2004-12-31 is invalid", "expected the stored date to be reported")
+	})
+
+	t.Run("repair", func(t *testing.T) {
+		idx := getTestIndexWithMode(t, true)
+		for _, query := range []string{
+			"DELETE FROM posts WHERE num IN (5, 6)",
+			"UPDATE posts SET image = 'ftp://imgs.xkcd.com/comics/7.png' WHERE num = 7",
+			"UPDATE posts SET link = '' WHERE num = 8",
+			"UPDATE posts SET date = 1 WHERE num = 11",
+			"UPDATE posts SET content = x'00010203' WHERE num = 9",
+			"UPDATE posts SET content = NULL WHERE num = 10",
+			"UPDATE last_update SET last_num = 32",
+		} {
+			_, err := idx.DB().ExecContext(ctx, query)
+			require.NoError(t, err)
+		}
+
+		problems, err := idx.Verify(ctx)
+		require.NoError(t, err, "expected no error")
+		assert.Equal(t, []uint{5, 6, 31, 32}, problemNums(problems, xkcdindex.ProblemMissingPost), "expected missing posts")
+		assert.Equal(t, []uint{7, 8, 11}, problemNums(problems, xkcdindex.ProblemInvalidPost), "expected invalid posts")
+		assert.Equal(t, []uint{9}, problemNums(problems, xkcdindex.ProblemInvalidImage), "expected invalid images")
+		assert.Equal(t, []uint{10}, problemNums(problems, xkcdindex.ProblemMissingImage), "expected missing images")
+
+		fixed, err := idx.Repair(ctx, getTestClient(t), problems)
+		assert.NoError(t, err, "expected no error")
+		assert.ElementsMatch(t, problems, fixed, "expected all problems to be fixed")
+
+		problems, err = idx.Verify(ctx)
+		assert.NoError(t, err, "expected no error")
+		assert.Empty(t, problems, "expected no problem after repair")
+		meta, err := idx.GetImageMetadata(ctx, 9)
+		assert.NoError(t, err, "expected no error")
+		assert.Equal(t, "image/png", meta.ContentType, "expected image to be stored again")
+	})
+}